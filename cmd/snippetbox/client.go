@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// client talks to a Snippetbox server: the JSON API for creating snippets,
+// and the raw endpoint for fetching them.
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newClient(cfg config) *client {
+	return &client{
+		baseURL: strings.TrimRight(cfg.URL, "/"),
+		token:   cfg.Token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// apiError is an error response from the server. Fields holds the messages
+// from a failed validation.
+type apiError struct {
+	Status  int               `json:"status"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields"`
+}
+
+func (e *apiError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	// list every field the server complained about, sorted so the output
+	// doesn't jump around
+	var b strings.Builder
+	b.WriteString(e.Message)
+	for _, field := range slices.Sorted(maps.Keys(e.Fields)) {
+		fmt.Fprintf(&b, "\n  %s: %s", field, e.Fields[field])
+	}
+	return b.String()
+}
+
+// createdSnippet is the part of the API's snippet object we use.
+type createdSnippet struct {
+	ID int `json:"id"`
+}
+
+// paste creates a snippet and returns the URL of its page.
+func (c *client) paste(title, content string, expires int) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"title":   title,
+		"content": content,
+		"expires": expires,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/api/v1/snippets", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp struct {
+		Snippet createdSnippet `json:"snippet"`
+	}
+	err = c.do(req, http.StatusCreated, &resp)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/snippet/view/%d", c.baseURL, resp.Snippet.ID), nil
+}
+
+// get copies the content of a snippet to w.
+func (c *client) get(id int, w io.Writer) error {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/snippet/raw/%d", c.baseURL, id), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return &apiError{Status: res.StatusCode, Message: fmt.Sprintf("snippet %d does not exist or has expired", id)}
+	}
+	if res.StatusCode != http.StatusOK {
+		return &apiError{Status: res.StatusCode, Message: http.StatusText(res.StatusCode)}
+	}
+
+	_, err = io.Copy(w, res.Body)
+	return err
+}
+
+// do sends req and decodes the JSON response into dst, or returns an
+// *apiError if the status isn't want.
+func (c *client) do(req *http.Request, want int, dst any) error {
+	res, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != want {
+		var envelope struct {
+			Error *apiError `json:"error"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&envelope)
+		if err != nil || envelope.Error == nil {
+			// not one of our JSON errors, e.g. from a proxy in front of the
+			// server
+			return &apiError{Status: res.StatusCode, Message: http.StatusText(res.StatusCode)}
+		}
+		return envelope.Error
+	}
+
+	return json.NewDecoder(res.Body).Decode(dst)
+}
+
+// send adds the API token (if we have one) and sends req.
+func (c *client) send(req *http.Request) (*http.Response, error) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set("User-Agent", "snippetbox-cli")
+
+	return c.http.Do(req)
+}
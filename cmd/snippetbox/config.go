@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// config holds the settings shared by every command.
+type config struct {
+	URL   string // base URL of the server, like http://localhost:4000
+	Token string // personal API token, needed to paste
+}
+
+// defaultURL is where the web app listens when started without flags.
+const defaultURL = "http://localhost:4000"
+
+// configPath returns the location of the config file:
+// $XDG_CONFIG_HOME/snippetbox/config on Linux, and the equivalent on other
+// systems. SNIPPETBOX_CONFIG overrides it.
+func configPath() (string, error) {
+	if path := os.Getenv("SNIPPETBOX_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snippetbox", "config"), nil
+}
+
+// loadConfig reads the config file, then lets the SNIPPETBOX_URL and
+// SNIPPETBOX_TOKEN environment variables override it. Command-line flags
+// override both; see main(). A missing config file is fine.
+func loadConfig() (config, error) {
+	cfg := config{URL: defaultURL}
+
+	path, err := configPath()
+	if err != nil {
+		return config{}, err
+	}
+
+	err = readConfigFile(path, &cfg)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return config{}, err
+	}
+
+	if v := os.Getenv("SNIPPETBOX_URL"); v != "" {
+		cfg.URL = v
+	}
+	if v := os.Getenv("SNIPPETBOX_TOKEN"); v != "" {
+		cfg.Token = v
+	}
+
+	return cfg, nil
+}
+
+// readConfigFile reads "key = value" lines from path into cfg. Blank lines
+// and lines starting with # are ignored. For example:
+//
+//	# ~/.config/snippetbox/config
+//	url = https://snippets.example.com
+//	token = sbx_...
+func readConfigFile(path string, cfg *config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected key = value", path, n)
+		}
+
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "url":
+			cfg.URL = value
+		case "token":
+			cfg.Token = value
+		default:
+			return fmt.Errorf("%s:%d: unknown setting %q", path, n, key)
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,191 @@
+// Command snippetbox pastes and fetches snippets on a Snippetbox server from
+// the terminal:
+//
+//	snippetbox paste -t "title" -e 7 < file.go
+//	snippetbox paste main.go
+//	snippetbox get 42
+//
+// The server URL and API token come from the -url and -token flags, the
+// SNIPPETBOX_URL and SNIPPETBOX_TOKEN environment variables, or the config
+// file (see configPath), in that order.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Exit codes, so scripts can tell what went wrong.
+const (
+	exitOK         = 0
+	exitError      = 1 // anything not covered below, like a network error
+	exitUsage      = 2 // bad command-line arguments
+	exitValidation = 3 // the server rejected the snippet (422)
+	exitNotFound   = 4 // no such snippet, or it has expired (404)
+	exitAuth       = 5 // missing, invalid or under-scoped API token (401/403)
+)
+
+const usage = `Usage:
+  snippetbox [-url URL] [-token TOKEN] paste [-t title] [-e days] [file]
+  snippetbox [-url URL] [-token TOKEN] get ID
+
+paste reads the snippet from file, or from stdin if no file is given, and
+prints the URL of the new snippet. get prints the content of a snippet.
+
+Exit codes: 0 success, 1 error, 2 usage, 3 validation failed, 4 not found,
+5 authentication failed.
+
+Global flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run does the work of main(), returning the exit code. Keeping os.Exit()
+// out of here means deferred calls always run.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(stderr, "snippetbox:", err)
+		return exitError
+	}
+
+	fs := flag.NewFlagSet("snippetbox", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.URL, "url", cfg.URL, "Base URL of the Snippetbox server")
+	fs.StringVar(&cfg.Token, "token", cfg.Token, "Personal API token")
+
+	err = fs.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	c := newClient(cfg)
+
+	switch command := fs.Arg(0); command {
+	case "paste":
+		err = paste(c, fs.Args()[1:], stdin, stdout, stderr)
+	case "get":
+		err = get(c, fs.Args()[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "snippetbox: unknown command %q\n", command)
+		fs.Usage()
+		return exitUsage
+	}
+
+	return exitCode(err, stderr)
+}
+
+// errUsage is returned by the commands for bad arguments, after they've
+// printed their own usage message.
+var errUsage = errors.New("usage")
+
+// exitCode reports err on stderr and picks the matching exit code.
+func exitCode(err error, stderr io.Writer) int {
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, errUsage) {
+		return exitUsage
+	}
+
+	fmt.Fprintln(stderr, "snippetbox:", err)
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Status {
+		case http.StatusUnprocessableEntity:
+			return exitValidation
+		case http.StatusNotFound:
+			return exitNotFound
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return exitAuth
+		}
+	}
+
+	return exitError
+}
+
+// paste creates a snippet and prints its URL.
+func paste(c *client, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("paste", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	title := fs.String("t", "", "Title of the snippet (defaults to the file name)")
+	expires := fs.Int("e", 365, "Days until the snippet expires: 1, 7 or 365")
+
+	err := fs.Parse(args)
+	if err != nil || fs.NArg() > 1 {
+		if err == nil {
+			fmt.Fprintln(stderr, "snippetbox: paste takes at most one file")
+		}
+		return errUsage
+	}
+
+	if c.token == "" {
+		return errors.New("paste needs an API token: use -token, SNIPPETBOX_TOKEN or the config file")
+	}
+
+	input := stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		input = f
+		if *title == "" {
+			*title = filepath.Base(fs.Arg(0))
+		}
+	}
+
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+
+	url, err := c.paste(*title, string(content), *expires)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, url)
+	return nil
+}
+
+// get prints the content of a snippet.
+func get(c *client, args []string, stdout, stderr io.Writer) error {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "snippetbox: get takes exactly one snippet id")
+		return errUsage
+	}
+
+	// accept a snippet's URL as well as its id
+	id, err := strconv.Atoi(args[0][strings.LastIndex(args[0], "/")+1:])
+	if err != nil || id < 1 {
+		fmt.Fprintf(stderr, "snippetbox: %q is not a snippet id\n", args[0])
+		return errUsage
+	}
+
+	return c.get(id, stdout)
+}
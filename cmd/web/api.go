@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"snippetbox.vishalborana2407.net/internal/models"
+)
+
+// snippetJSON is how a snippet is represented in API responses. Keeping it
+// apart from models.Snippet means changes to the model don't silently change
+// the API.
+type snippetJSON struct {
+	ID       int       `json:"id"`
+	Title    string    `json:"title"`
+	Content  string    `json:"content"`
+	Author   string    `json:"author,omitempty"`
+	Tags     []string  `json:"tags"`
+	Language string    `json:"language"`
+	Format   string    `json:"format"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires"`
+}
+
+func newSnippetJSON(s models.Snippet) snippetJSON {
+	return snippetJSON{
+		ID:      s.ID,
+		Title:   s.Title,
+		Content: s.Content,
+		Author:  s.Author,
+		// an empty array rather than null for snippets without tags
+		Tags:     append([]string{}, s.Tags...),
+		Language: s.Language,
+		Format:   s.Format,
+		Created:  s.Created.UTC(),
+		Expires:  s.Expires.UTC(),
+	}
+}
+
+// apiSnippetList returns the latest snippets, like the home page. With
+// ?tag=name it returns the latest app.pageSize snippets with that tag
+// instead, like the tag pages.
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	var (
+		snippets []models.Snippet
+		err      error
+	)
+
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		snippets, err = app.snippets.ByTag(r.Context(), strings.ToLower(tag), app.pageSize, 0)
+	} else {
+		snippets, err = app.snippets.Latest(r.Context())
+	}
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	// make() rather than a nil slice, so no snippets is [] rather than null
+	list := make([]snippetJSON, 0, len(snippets))
+	for _, s := range snippets {
+		list = append(list, newSnippetJSON(s))
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"snippets": list})
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// apiSnippetGet returns a single snippet.
+func (app *application) apiSnippetGet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.apiNotFound(w, r)
+		return
+	}
+
+	snippet, err := app.snippets.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w, r)
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"snippet": newSnippetJSON(snippet)})
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
+
+// apiSnippetCreate creates a snippet from a JSON body like
+// {"title": "...", "content": "...", "expires": 7}, owned by the
+// authenticated user. It responds with 201 Created, the new snippet and its
+// URL in the Location header.
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	var form snippetCreateForm
+
+	err := app.readJSON(w, r, &form)
+	if err != nil {
+		if errors.Is(err, errJSONContentType) {
+			app.apiError(w, r, http.StatusUnsupportedMediaType, err.Error())
+		} else {
+			app.apiError(w, r, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	form.validate(false)
+
+	if !form.Valid() {
+		app.apiFailedValidation(w, r, form.FieldErrors)
+		return
+	}
+
+	// Remember which API token created the snippet, if any.
+	in := form.input()
+	in.TokenID = app.authenticatedTokenID(r)
+	id, err := app.snippets.Insert(r.Context(), in, app.authenticatedUserID(r))
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	app.logSnippetCreated(r, id)
+
+	// Read the snippet back so the response has the timestamps set by the
+	// database.
+	snippet, err := app.snippets.Get(r.Context(), id)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/snippets/%d", id))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"snippet": newSnippetJSON(snippet)})
+	if err != nil {
+		app.apiServerError(w, r, err)
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+// Define our own contextKey type, rather than using a plain string, so our
+// request context keys can't clash with keys set by other packages.
+type contextKey string
+
+// isAuthenticatedContextKey is set to true in the request context by the
+// authenticate middleware when the request comes from a logged-in user who
+// still exists in the database.
+const isAuthenticatedContextKey = contextKey("isAuthenticated")
+
+// userIDContextKey holds the id of that user, set alongside
+// isAuthenticatedContextKey.
+const userIDContextKey = contextKey("userID")
+
+// tokenIDContextKey holds the id of the API token the request was
+// authenticated with. It isn't set for requests authenticated by the session.
+const tokenIDContextKey = contextKey("tokenID")
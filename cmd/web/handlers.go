@@ -1,11 +1,21 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
+	"github.com/justinas/nosurf"
+	"snippetbox.vishalborana2407.net/internal/diff"
+	"snippetbox.vishalborana2407.net/internal/highlight"
+	"snippetbox.vishalborana2407.net/internal/markdown"
 	"snippetbox.vishalborana2407.net/internal/models"
 	"snippetbox.vishalborana2407.net/internal/validator"
 )
@@ -15,19 +25,179 @@ import (
 // example, here we're telling the decoder to store the value from the HTML form
 // input with the name "title" in the Title field. The struct tag `form:"-"`
 // tells the decoder to completely ignore a field during decoding.
+//
+// The json tags let the API decode request bodies into the same struct, so
+// snippets created through it are held to the same rules.
+//
+// Language is a language name or alias known to Chroma, or "" to have it
+// detected from the title and content. Format is "plain" or "markdown", or
+// "" to go by the language (see input).
+//
+// Tags come from a single text box in the HTML form ("sql, k8s") and from an
+// array in the API (["sql", "k8s"]). Both decode into a []string, which
+// validate() splits up and cleans (see cleanTags).
 type snippetCreateForm struct {
-	Title               string `form:"title"`
-	Content             string `form:"content"`
-	Expires             int    `form:"expires"`
+	Title               string   `form:"title" json:"title"`
+	Content             string   `form:"content" json:"content"`
+	Expires             int      `form:"expires" json:"expires"`
+	Tags                []string `form:"tags" json:"tags"`
+	Language            string   `form:"language" json:"language"`
+	Format              string   `form:"format" json:"format"`
+	validator.Validator `form:"-" json:"-"`
+}
+
+// Limits on the tags of a snippet. maxTagLength matches the size of the
+// tags.name column.
+const (
+	maxTags      = 5
+	maxTagLength = 32
+)
+
+// keepExpiry is the Expires value of the edit form's "keep the current
+// expiry" option. Update() leaves the expiry alone when it sees it.
+const keepExpiry = 0
+
+// validate runs the checks shared by the create and edit forms. Only the edit
+// form (editing is true) accepts keepExpiry.
+func (form *snippetCreateForm) validate(editing bool) {
+	// Because the Validator struct is embedded by the snippetCreateForm struct,
+	// we can call CheckField() directly on it to execute our validation checks.
+	// CheckField() will add the provided key and error message to the
+	// FieldErrors map if the check does not evaluate to true. For example, in
+	// the first line here we "check that the form.Title field is not blank". In
+	// the second, we "check that the form.Title field has a maximum character
+	// length of 100" and so on.
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365) || editing && form.Expires == keepExpiry, "expires", "This field must equal 1, 7 or 365")
+
+	form.Tags = cleanTags(form.Tags)
+	form.CheckField(validator.MaxItems(form.Tags, maxTags), "tags", fmt.Sprintf("This field cannot have more than %d tags", maxTags))
+	for _, tag := range form.Tags {
+		form.CheckField(validator.MaxChars(tag, maxTagLength), "tags", fmt.Sprintf("Tags cannot be more than %d characters long", maxTagLength))
+		form.CheckField(validator.Matches(tag, validator.TagRX), "tags", "Tags can only contain letters, digits and + # . -")
+	}
+
+	// Store the canonical name, whichever alias was given.
+	if form.Language != "" {
+		name, ok := highlight.Lookup(form.Language)
+		form.CheckField(ok, "language", "This language isn't supported")
+		if ok {
+			form.Language = name
+		}
+	}
+
+	form.CheckField(validator.PermittedValue(form.Format, "", models.FormatPlain, models.FormatMarkdown), "format", "This field must equal plain or markdown")
+}
+
+// input returns the snippet described by the form, for the models. If no
+// language was picked, it's detected now and stored, so it doesn't change
+// from one view of the snippet to the next. Without a format, a snippet in
+// Markdown is rendered and anything else is shown as it is.
+func (form *snippetCreateForm) input() models.SnippetInput {
+	language := form.Language
+	if language == "" {
+		language = highlight.Detect(form.Title, form.Content)
+	}
+
+	format := form.Format
+	if format == "" {
+		format = models.FormatPlain
+		if language == "markdown" {
+			format = models.FormatMarkdown
+		}
+	}
+
+	return models.SnippetInput{
+		Title:    form.Title,
+		Content:  form.Content,
+		Expires:  form.Expires,
+		Tags:     form.Tags,
+		Language: language,
+		Format:   format,
+	}
+}
+
+// LanguageOptions lists the languages for the select box in the HTML form.
+// That's highlight.Languages, plus the snippet's current language if it's
+// something else (say, picked through the API) so editing doesn't lose it.
+func (form snippetCreateForm) LanguageOptions() []string {
+	if form.Language == "" || form.Language == "plaintext" || slices.Contains(highlight.Languages, form.Language) {
+		return highlight.Languages
+	}
+	return append(slices.Clone(highlight.Languages), form.Language)
+}
+
+// TagList joins the tags back up for the text box in the HTML form.
+func (form snippetCreateForm) TagList() string {
+	return strings.Join(form.Tags, ", ")
+}
+
+// cleanTags splits each of tags on commas and whitespace, lower-cases them
+// and drops empty and repeated ones, keeping the order they were given in.
+func cleanTags(tags []string) []string {
+	var clean []string
+	for _, t := range tags {
+		for _, tag := range strings.FieldsFunc(strings.ToLower(t), func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		}) {
+			if !slices.Contains(clean, tag) {
+				clean = append(clean, tag)
+			}
+		}
+	}
+	return clean
+}
+
+// userSignupForm holds the data from the signup form, along with any
+// validation errors.
+type userSignupForm struct {
+	Name                string `form:"name"`
+	Email               string `form:"email"`
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+// userLoginForm holds the data from the login form, along with any
+// validation errors.
+type userLoginForm struct {
+	Email               string `form:"email"`
+	Password            string `form:"password"`
 	validator.Validator `form:"-"`
 }
 
+// tokenCreateForm holds the data from the form for creating an API token.
+// Expires is the number of days until the token expires, or 0 for never.
+type tokenCreateForm struct {
+	Name                string   `form:"name"`
+	Scopes              []string `form:"scopes"`
+	Expires             int      `form:"expires"`
+	validator.Validator `form:"-"`
+}
+
+// validate checks the token form. It's shared with the token subcommand.
+func (form *tokenCreateForm) validate() {
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 100), "name", "This field cannot be more than 100 characters long")
+	form.CheckField(len(form.Scopes) > 0, "scopes", "Pick at least one scope")
+	for _, scope := range form.Scopes {
+		form.CheckField(validator.PermittedValue(scope, models.ScopeRead, models.ScopeWrite), "scopes", "Scopes must be read or write")
+	}
+	form.CheckField(validator.PermittedValue(form.Expires, 0, 7, 30, 90, 365), "expires", "This field must equal 0, 7, 30, 90 or 365")
+}
+
+// HasScope reports whether scope is ticked, for the checkboxes in the form.
+func (form tokenCreateForm) HasScope(scope string) bool {
+	return slices.Contains(form.Scopes, scope)
+}
+
 // home handles requests to the root URL ("/").
 // Change the signature of the home handler so it is defined as a method against
 // *application.
 func (app *application) home(w http.ResponseWriter, r *http.Request) {
 	// Get latest snippet - top 10
-	snippets, err := app.snippets.Latest()
+	snippets, err := app.snippets.Latest(r.Context())
 
 	if err != nil {
 		app.serverError(w, r, err)
@@ -57,7 +227,7 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	snippet, err := app.snippets.Get(id)
+	snippet, err := app.snippets.Get(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			http.NotFound(w, r)
@@ -71,10 +241,119 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 
 	data.Snippet = snippet
 
+	// Show the owner which of their API tokens created the snippet. Tokens
+	// are private, so nobody else sees it.
+	if snippet.TokenID != 0 && snippet.UserID == app.authenticatedUserID(r) {
+		tokens, err := app.tokens.ByUser(r.Context(), snippet.UserID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		for _, t := range tokens {
+			if t.ID == snippet.TokenID {
+				data.TokenName = t.Name
+			}
+		}
+	}
+
+	// A Markdown snippet is shown rendered, unless ?source=1 asks for the
+	// Markdown itself or ?lines= asks for some of its lines.
+	lines := r.URL.Query().Get("lines")
+	data.ShowSource = snippet.Format != models.FormatMarkdown || r.URL.Query().Get("source") == "1" || lines != ""
+
+	// Render the content here rather than in the template, so a failure is
+	// handled like any other server error.
+	switch {
+	case lines != "":
+		var rng highlight.Range
+		rng, err = highlight.ParseRange(lines)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+
+		// A range running past the end is cut short, but one starting
+		// after it has nothing to show.
+		total := highlight.LineCount(snippet.Content)
+		if rng.From > total {
+			http.NotFound(w, r)
+			return
+		}
+		rng.To = min(rng.To, total)
+
+		data.Lines = linesPage{Range: rng, Total: total}
+		data.Code, err = highlight.HTMLLines(snippet.Content, snippet.Language, rng)
+	case data.ShowSource:
+		data.Code, err = highlight.HTML(snippet.Content, snippet.Language)
+	default:
+		data.Code, err = markdown.Render(snippet.Content)
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
 	// Use the render helper.
 	app.render(w, r, http.StatusOK, "view.tmpl", data)
 }
 
+// rawMaxAge is the longest a raw snippet may be cached for. Snippets can be
+// edited, so caches have to check back now and then.
+const rawMaxAge = time.Minute
+
+// snippetRaw sends just the content of a snippet as plain text, for curl and
+// shell pipelines. With ?download=1 browsers save it as a file instead of
+// showing it.
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	snippet, err := app.snippets.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// The snippet was last changed when its newest revision was made (the
+	// list is newest first). Snippets from before revisions existed have one
+	// too, made by the migration.
+	modified := snippet.Created
+	revisions, err := app.snippets.Revisions(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if len(revisions) > 0 {
+		modified = revisions[0].Created
+	}
+
+	// Never let a cache keep the snippet past its expiry time.
+	maxAge := min(rawMaxAge, time.Until(snippet.Expires))
+
+	sum := sha256.Sum256([]byte(snippet.Content))
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "1" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`%s; filename="snippet-%d.txt"`, disposition, id))
+
+	// ServeContent() sets Last-Modified and handles conditional requests
+	// (If-None-Match, If-Modified-Since) and Range requests for us.
+	http.ServeContent(w, r, "", modified, strings.NewReader(snippet.Content))
+}
+
 // snippetCreate displays a form for creating a new snippet.
 func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
@@ -84,6 +363,7 @@ func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	// snippet expiry to 365 days.
 	data.Form = snippetCreateForm{
 		Expires: 365,
+		Format:  models.FormatPlain,
 	}
 
 	// render the create.tmpl template
@@ -105,17 +385,7 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Because the Validator struct is embedded by the snippetCreateForm struct,
-	// we can call CheckField() directly on it to execute our validation checks.
-	// CheckField() will add the provided key and error message to the
-	// FieldErrors map if the check does not evaluate to true. For example, in
-	// the first line here we "check that the form.Title field is not blank". In
-	// the second, we "check that the form.Title field has a maximum character
-	// length of 100" and so on.
-	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
-	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
-	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+	form.validate(false)
 
 	// Use the Valid() method to see if any of the checks failed. If they did,
 	// then re-render the template passing in the form in the same way as
@@ -128,11 +398,665 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 	}
 
 	// If there are no validation errors, then save the snippet to the database.
-	id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+	// Passing r.Context() means the query is abandoned if the client goes away.
+	// The snippet belongs to the logged-in user, and remembers the API token
+	// it was created with, if any.
+	in := form.input()
+	in.TokenID = app.authenticatedTokenID(r)
+	id, err := app.snippets.Insert(r.Context(), in, app.authenticatedUserID(r))
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
+
+	app.logSnippetCreated(r, id)
+
+	// Add a flash message to the session, which is shown on the next page.
+	app.putFlash(r, flashSuccess, "Snippet successfully created!")
+
 	// Redirect the user to the relevant page for the snippet.
 	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
 }
+
+// snippetEdit displays the edit form for one of the logged-in user's
+// snippets, filled in with its current title and content.
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	snippet, err := app.snippets.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// Only the owner gets to see the form. The model checks this again when
+	// the form is submitted.
+	if snippet.UserID == 0 || snippet.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	// Fixing a typo shouldn't change how long the snippet is kept, so the
+	// expiry stays as it is unless the user picks a new one.
+	data.Form = snippetCreateForm{
+		Title:    snippet.Title,
+		Content:  snippet.Content,
+		Expires:  keepExpiry,
+		Tags:     snippet.Tags,
+		Language: snippet.Language,
+		Format:   snippet.Format,
+	}
+
+	app.render(w, r, http.StatusOK, "edit.tmpl", data)
+}
+
+// snippetEditPost validates the edit form with the same rules as the create
+// form and saves the changes.
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var form snippetCreateForm
+
+	err = app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.validate(true)
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = models.Snippet{ID: id}
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "edit.tmpl", data)
+		return
+	}
+
+	err = app.snippets.Update(r.Context(), id, app.authenticatedUserID(r), form.input())
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			http.NotFound(w, r)
+		case errors.Is(err, models.ErrNotOwner):
+			app.clientError(w, http.StatusForbidden)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.putFlash(r, flashSuccess, "Snippet successfully updated!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+}
+
+// snippetHistory lists every revision of a snippet, newest first, with a
+// form for picking two of them to compare.
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	// The history of a snippet goes away with the snippet.
+	snippet, err := app.snippets.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+
+	app.render(w, r, http.StatusOK, "history.tmpl", data)
+}
+
+// diffContext is how many unchanged lines the unified diff shows around each
+// change.
+const diffContext = 3
+
+// snippetDiff shows the differences between two revisions of a snippet. The
+// revisions come from the ?from= and ?to= query string parameters, and
+// ?mode= picks a "unified" (the default) or "split" side-by-side view.
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+
+	from, err := strconv.Atoi(query.Get("from"))
+	if err != nil || from < 1 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	to, err := strconv.Atoi(query.Get("to"))
+	if err != nil || to < 1 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = "unified"
+	}
+	if mode != "unified" && mode != "split" {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	snippet, err := app.snippets.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	page := diffPage{Mode: mode}
+
+	page.From, err = app.snippets.Revision(r.Context(), id, from)
+	if err == nil {
+		page.To, err = app.snippets.Revision(r.Context(), id, to)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	lines := diff.Lines(page.From.Content, page.To.Content)
+	if mode == "split" {
+		page.Rows = diff.SideBySide(lines)
+	} else {
+		page.Hunks = diff.Unified(lines, diffContext)
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Diff = page
+
+	app.render(w, r, http.StatusOK, "diff.tmpl", data)
+}
+
+// snippetsMine lists the logged-in user's snippets, app.pageSize at a time.
+// The page number comes from the ?page= query string parameter.
+func (app *application) snippetsMine(w http.ResponseWriter, r *http.Request) {
+	page, ok := pageNumber(r)
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// Ask for one more snippet than we show, so we know whether there's a
+	// next page without a separate COUNT(*) query.
+	snippets, err := app.snippets.ByUser(r.Context(), app.authenticatedUserID(r), app.pageSize+1, (page-1)*app.pageSize)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Pagination = pagination{Page: page, HasNext: len(snippets) > app.pageSize}
+	if data.Pagination.HasNext {
+		snippets = snippets[:app.pageSize]
+	}
+	data.Snippets = snippets
+
+	app.render(w, r, http.StatusOK, "mine.tmpl", data)
+}
+
+// snippetsBrowse lists every snippet, newest first, app.pageSize at a time.
+// It uses keyset pagination: ?after= and ?before= hold the cursor of the
+// last snippet of the previous page or the first snippet of the next one
+// (see models.Cursor), so there's no OFFSET for the database to count
+// through however far back you go.
+func (app *application) snippetsBrowse(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	after, before := query.Get("after"), query.Get("before")
+	if after != "" && before != "" {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	var (
+		snippets []models.Snippet
+		page     browsePage
+		err      error
+	)
+
+	// Fetch one snippet more than we show, to find out whether there's
+	// another page in the direction we're going.
+	if before != "" {
+		cursor, err := models.ParseCursor(before)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+
+		snippets, err = app.snippets.ListBefore(r.Context(), cursor, app.pageSize+1)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		// The extra snippet is the newest one, at the start.
+		page.HasPrev = len(snippets) > app.pageSize
+		if page.HasPrev {
+			snippets = snippets[1:]
+		}
+		page.HasNext = true
+	} else {
+		var cursor models.Cursor
+		if after != "" {
+			cursor, err = models.ParseCursor(after)
+			if err != nil {
+				app.clientError(w, http.StatusBadRequest)
+				return
+			}
+		}
+
+		snippets, err = app.snippets.ListAfter(r.Context(), cursor, app.pageSize+1)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		page.HasNext = len(snippets) > app.pageSize
+		if page.HasNext {
+			snippets = snippets[:app.pageSize]
+		}
+		page.HasPrev = !cursor.IsZero()
+	}
+
+	// The links pick up from the snippets at either end of this page. If
+	// the page is empty (say everything on it was deleted) there's nothing
+	// to pick up from, so start again from the newest.
+	if len(snippets) > 0 {
+		page.Prev = snippets[0].Cursor().String()
+		page.Next = snippets[len(snippets)-1].Cursor().String()
+	} else {
+		page.HasNext = false
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+	data.Browse = page
+
+	app.render(w, r, http.StatusOK, "browse.tmpl", data)
+}
+
+// tagView lists the snippets with the tag in the URL, newest first,
+// app.pageSize at a time.
+func (app *application) tagView(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(r.PathValue("name"))
+	if !validator.Matches(tag, validator.TagRX) || !validator.MaxChars(tag, maxTagLength) {
+		http.NotFound(w, r)
+		return
+	}
+
+	page, ok := pageNumber(r)
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	snippets, err := app.snippets.ByTag(r.Context(), tag, app.pageSize+1, (page-1)*app.pageSize)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Tag = tag
+	data.Pagination = pagination{Page: page, HasNext: len(snippets) > app.pageSize}
+	if data.Pagination.HasNext {
+		snippets = snippets[:app.pageSize]
+	}
+	data.Snippets = snippets
+
+	app.render(w, r, http.StatusOK, "tag.tmpl", data)
+}
+
+// search lists the snippets matching the ?q= query string parameter, best
+// match first, app.pageSize at a time. Without a query it just shows the
+// search form.
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+	page, ok := pageNumber(r)
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	// As in snippetsMine, fetch one extra result to find out if there's
+	// another page.
+	snippets, err := app.snippets.Search(r.Context(), query, app.pageSize+1, (page-1)*app.pageSize)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Search = searchPage{Query: query, Terms: models.SearchTerms(query)}
+	data.Pagination = pagination{Page: page, HasNext: len(snippets) > app.pageSize}
+	if data.Pagination.HasNext {
+		snippets = snippets[:app.pageSize]
+	}
+	data.Snippets = snippets
+
+	app.render(w, r, http.StatusOK, "search.tmpl", data)
+}
+
+// snippetDeletePost deletes one of the logged-in user's snippets. Trying to
+// delete somebody else's snippet gets a 403 Forbidden.
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = app.snippets.Delete(r.Context(), id, app.authenticatedUserID(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			http.NotFound(w, r)
+		case errors.Is(err, models.ErrNotOwner):
+			app.clientError(w, http.StatusForbidden)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.putFlash(r, flashSuccess, "Snippet deleted.")
+
+	http.Redirect(w, r, "/snippets/mine", http.StatusSeeOther)
+}
+
+// accountTokens lists the logged-in user's API tokens, along with a form to
+// create a new one.
+func (app *application) accountTokens(w http.ResponseWriter, r *http.Request) {
+	app.renderTokens(w, r, http.StatusOK, tokenCreateForm{
+		Scopes:  []string{models.ScopeRead},
+		Expires: 90,
+	}, "")
+}
+
+// accountTokensPost creates an API token. Unlike our other forms we don't
+// redirect afterwards: the new token is shown on the page we render, and
+// it's never available again.
+func (app *application) accountTokensPost(w http.ResponseWriter, r *http.Request) {
+	var form tokenCreateForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.validate()
+
+	if !form.Valid() {
+		app.renderTokens(w, r, http.StatusUnprocessableEntity, form, "")
+		return
+	}
+
+	token, err := app.tokens.Insert(r.Context(), app.authenticatedUserID(r), form.Name, form.Scopes, form.Expires)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.renderTokens(w, r, http.StatusOK, tokenCreateForm{
+		Scopes:  []string{models.ScopeRead},
+		Expires: 90,
+	}, token)
+}
+
+// renderTokens renders the tokens page with the user's current tokens.
+func (app *application) renderTokens(w http.ResponseWriter, r *http.Request, status int, form tokenCreateForm, newToken string) {
+	tokens, err := app.tokens.ByUser(r.Context(), app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
+	data.Tokens = tokens
+	data.NewToken = newToken
+
+	app.render(w, r, status, "tokens.tmpl", data)
+}
+
+// accountTokenRevokePost revokes one of the logged-in user's API tokens.
+func (app *application) accountTokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	// The token may have been revoked already, say from another tab. Rather
+	// than a bare 404 page, send the user back to the list with an error
+	// flash saying so.
+	err = app.tokens.Revoke(r.Context(), id, app.authenticatedUserID(r))
+	if errors.Is(err, models.ErrNoRecord) {
+		app.putFlash(r, flashError, "That API token doesn't exist or was already revoked.")
+		http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.putFlash(r, flashSuccess, "API token revoked.")
+
+	http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
+}
+
+// userSignup displays the signup form.
+func (app *application) userSignup(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = userSignupForm{}
+	app.render(w, r, http.StatusOK, "signup.tmpl", data)
+}
+
+// userSignupPost validates the signup form and creates a new user.
+func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
+	// Declare a zero-valued instance of our userSignupForm struct.
+	var form userSignupForm
+
+	// Parse the form data into the userSignupForm struct.
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.Email = normalizeEmail(form.Email)
+
+	// Validate the form contents using our helper functions.
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 255), "name", "This field cannot be more than 255 characters long")
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cannot be blank")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address")
+	form.CheckField(validator.MaxChars(form.Email, 255), "email", "This field cannot be more than 255 characters long")
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
+	form.CheckField(validator.MinChars(form.Password, 8), "password", "This field must be at least 8 characters long")
+	// bcrypt only looks at the first 72 bytes of a password
+	form.CheckField(len(form.Password) <= 72, "password", "This field cannot be more than 72 bytes long")
+
+	// If there are any errors, redisplay the signup form along with a 422
+	// status code.
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "signup.tmpl", data)
+		return
+	}
+
+	// Try to create a new user record in the database. If the email already
+	// exists then add an error message to the form and re-display it.
+	err = app.users.Insert(r.Context(), form.Name, form.Email, form.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateEmail) {
+			form.AddFieldError("email", "Email address is already in use")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, r, http.StatusUnprocessableEntity, "signup.tmpl", data)
+		} else {
+			app.serverError(w, r, err)
+		}
+
+		return
+	}
+
+	// Otherwise add a flash message to the session confirming that their
+	// signup worked.
+	app.putFlash(r, flashSuccess, "Your signup was successful. Please log in.")
+
+	// And redirect the user to the login page.
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+// userLogin displays the login form.
+func (app *application) userLogin(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = userLoginForm{}
+	app.render(w, r, http.StatusOK, "login.tmpl", data)
+}
+
+// userLoginPost checks the user's credentials and logs them in.
+func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
+	// Decode the form data into the userLoginForm struct.
+	var form userLoginForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form.Email = normalizeEmail(form.Email)
+
+	// Do some validation checks on the form. We check that both email and
+	// password are provided, and also check the format of the email address as
+	// a UX-nicety (in case the user makes a typo).
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cannot be blank")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRX), "email", "This field must be a valid email address")
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "login.tmpl", data)
+		return
+	}
+
+	// Check whether the credentials are valid. If they're not, add a generic
+	// non-field error message and re-display the login page.
+	id, err := app.users.Authenticate(r.Context(), form.Email, form.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			form.AddNonFieldError("Email or password is incorrect")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, r, http.StatusUnprocessableEntity, "login.tmpl", data)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// Use the RenewToken() method on the current session to change the session
+	// ID. It's good practice to generate a new session ID when the
+	// authentication state or privilege level changes for the user (e.g. login
+	// and logout operations).
+	err = app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Add the ID of the current user to the session, so that they are now
+	// 'logged in'.
+	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
+
+	// Redirect the user to the create snippet page.
+	http.Redirect(w, r, "/snippet/create", http.StatusSeeOther)
+}
+
+// userLogoutPost logs the user out.
+func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
+	// Use the RenewToken() method on the current session to change the session
+	// ID again.
+	err := app.sessionManager.RenewToken(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Remove the authenticatedUserID from the session data so that the user is
+	// 'logged out'.
+	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+
+	// Add a flash message to the session to confirm to the user that they've been
+	// logged out.
+	app.putFlash(r, flashSuccess, "You've been logged out successfully!")
+
+	// Redirect the user to the application home page.
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// csrfFailure is called by the noSurf middleware when a form is posted without
+// a valid CSRF token (for example, from another site or from a stale page). It
+// logs the reason and renders a 400 Bad Request page.
+func (app *application) csrfFailure(w http.ResponseWriter, r *http.Request) {
+	app.logger.Warn("CSRF check failed", "reason", nosurf.Reason(r).Error(), "method", r.Method, "uri", r.URL.RequestURI())
+
+	data := app.newTemplateData(r)
+	data.Error = errorPage{
+		Status:  http.StatusBadRequest,
+		Message: "Your form submission could not be verified. Please go back, reload the page and try again.",
+	}
+	app.render(w, r, http.StatusBadRequest, "error.tmpl", data)
+}
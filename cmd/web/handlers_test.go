@@ -0,0 +1,361 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"snippetbox.vishalborana2407.net/internal/models"
+)
+
+// TestUserEmailCase checks that email addresses are matched whatever their
+// case, on the memory store as on MySQL.
+func TestUserEmailCase(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	signupForm := func(email string) url.Values {
+		form := url.Values{}
+		form.Add("name", "Alice")
+		form.Add("email", email)
+		form.Add("password", "pa$$word")
+		form.Add("csrf_token", ts.csrfToken(t, "/user/signup"))
+		return form
+	}
+
+	code, _, _ := ts.postForm(t, "/user/signup", signupForm(" Alice@Example.com "))
+	if code != http.StatusSeeOther {
+		t.Fatalf("signing up: got status %d; want %d", code, http.StatusSeeOther)
+	}
+
+	code, _, body := ts.postForm(t, "/user/signup", signupForm("alice@example.com"))
+	if code != http.StatusUnprocessableEntity || !strings.Contains(body, "Email address is already in use") {
+		t.Errorf("signing up again in lower case: got status %d; want %d", code, http.StatusUnprocessableEntity)
+	}
+
+	ts.login(t, "ALICE@example.COM")
+}
+
+// testPageBounds checks that the ?page= query of the paged list at urlPath
+// (which ends in "page=") is validated before it's turned into an offset: a
+// huge page number used to overflow and panic the memory models.
+func testPageBounds(t *testing.T, ts *testServer, urlPath string) {
+	tests := []struct {
+		page     string
+		wantCode int
+	}{
+		{page: "", wantCode: http.StatusOK},
+		{page: "1", wantCode: http.StatusOK},
+		{page: "2", wantCode: http.StatusOK},
+		{page: "10000", wantCode: http.StatusOK},
+		{page: "10001", wantCode: http.StatusBadRequest},
+		{page: "461168601842738792", wantCode: http.StatusBadRequest},
+		{page: "0", wantCode: http.StatusBadRequest},
+		{page: "-1", wantCode: http.StatusBadRequest},
+		{page: "two", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run("page="+tt.page, func(t *testing.T) {
+			code, _, _ := ts.get(t, urlPath+tt.page)
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestSnippetMinePageBounds(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	signup(t, app, "Alice", "alice@example.com")
+	ts.login(t, "alice@example.com")
+
+	testPageBounds(t, ts, "/snippets/mine?page=")
+}
+
+func TestSnippetCreatePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	signup(t, app, "Alice", "alice@example.com")
+	ts.login(t, "alice@example.com")
+
+	tests := []struct {
+		name         string
+		form         map[string]string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Valid",
+			form:         map[string]string{"title": "Hello", "content": "package main", "expires": "7", "tags": "go, Demo"},
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/1",
+		},
+		{
+			name:     "Blank title",
+			form:     map[string]string{"title": "", "content": "package main", "expires": "7"},
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			// "keep the current expiry" only makes sense when editing
+			name:     "Keep expiry",
+			form:     map[string]string{"title": "Hello", "content": "package main", "expires": "0"},
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Unknown format",
+			form:     map[string]string{"title": "Hello", "content": "package main", "expires": "7", "format": "html"},
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Too many tags",
+			form:     map[string]string{"title": "Hello", "content": "package main", "expires": "7", "tags": "a b c d e f"},
+			wantCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			for k, v := range tt.form {
+				form.Add(k, v)
+			}
+			form.Add("csrf_token", ts.csrfToken(t, "/snippet/create"))
+
+			code, header, _ := ts.postForm(t, "/snippet/create", form)
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+			if got := header.Get("Location"); got != tt.wantLocation {
+				t.Errorf("got Location %q; want %q", got, tt.wantLocation)
+			}
+		})
+	}
+
+	s, err := app.snippets.Get(t.Context(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"demo", "go"}; !slices.Equal(s.Tags, want) {
+		t.Errorf("got tags %q; want %q", s.Tags, want)
+	}
+	if s.Format != models.FormatPlain {
+		t.Errorf("got format %q; want %q", s.Format, models.FormatPlain)
+	}
+}
+
+// TestSnippetEditKeepsExpiry checks that saving the edit form with its
+// default "keep current" expiry doesn't move the expiry date.
+func TestSnippetEditKeepsExpiry(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	userID := signup(t, app, "Alice", "alice@example.com")
+	ts.login(t, "alice@example.com")
+
+	id, err := app.snippets.Insert(t.Context(), models.SnippetInput{Title: "Hello", Content: "typo", Expires: 1}, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := app.snippets.Get(t.Context(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, body := ts.get(t, "/snippet/edit/1")
+	if !strings.Contains(body, "name='expires' value='0' checked") {
+		t.Error("the edit form doesn't default to keeping the current expiry")
+	}
+
+	form := url.Values{}
+	form.Add("title", "Hello")
+	form.Add("content", "fixed")
+	form.Add("expires", "0")
+	form.Add("csrf_token", ts.csrfToken(t, "/snippet/edit/1"))
+
+	code, _, _ := ts.postForm(t, "/snippet/edit/1", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("got status %d; want %d", code, http.StatusSeeOther)
+	}
+
+	after, err := app.snippets.Get(t.Context(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Content != "fixed" {
+		t.Errorf("got content %q; want %q", after.Content, "fixed")
+	}
+	if !after.Expires.Equal(before.Expires) {
+		t.Errorf("got expiry %v; want it kept at %v", after.Expires, before.Expires)
+	}
+}
+
+// TestAccountTokensRequireSession checks that an API token can't be used to
+// create or revoke tokens, only a logged-in session can.
+func TestAccountTokensRequireSession(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	userID := signup(t, app, "Alice", "alice@example.com")
+	token, err := app.tokens.Insert(t.Context(), userID, "script", []string{models.ScopeRead, models.ScopeWrite}, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bearer := "Authorization: Bearer " + token
+
+	form := url.Values{}
+	form.Add("name", "forever")
+	form.Add("scopes", models.ScopeWrite)
+	form.Add("expires", "0")
+
+	code, _, _ := ts.get(t, "/account/tokens", bearer)
+	if code != http.StatusForbidden {
+		t.Errorf("GET with a token: got status %d; want %d", code, http.StatusForbidden)
+	}
+	code, _, _ = ts.postForm(t, "/account/tokens", form, bearer)
+	if code != http.StatusForbidden {
+		t.Errorf("POST with a token: got status %d; want %d", code, http.StatusForbidden)
+	}
+	code, _, _ = ts.postForm(t, "/account/tokens/1/revoke", url.Values{}, bearer)
+	if code != http.StatusForbidden {
+		t.Errorf("revoking with a token: got status %d; want %d", code, http.StatusForbidden)
+	}
+
+	tokens, err := app.tokens.ByUser(t.Context(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 {
+		t.Errorf("got %d tokens; want just the original", len(tokens))
+	}
+
+	// The same pages work after logging in.
+	ts.login(t, "alice@example.com")
+	code, _, _ = ts.get(t, "/account/tokens")
+	if code != http.StatusOK {
+		t.Errorf("GET when logged in: got status %d; want %d", code, http.StatusOK)
+	}
+}
+
+// TestAPISnippetCreateToken checks that a snippet created with an API token
+// records the token.
+func TestAPISnippetCreateToken(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	userID := signup(t, app, "Alice", "alice@example.com")
+	token, err := app.tokens.Insert(t.Context(), userID, "script", []string{models.ScopeWrite}, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/snippets",
+		strings.NewReader(`{"title": "Hello", "content": "package main", "expires": 7}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	code, _, body := ts.do(t, req)
+	if code != http.StatusCreated {
+		t.Fatalf("got status %d; want %d: %s", code, http.StatusCreated, body)
+	}
+
+	tokens, err := app.tokens.ByUser(t.Context(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := app.snippets.Get(t.Context(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.TokenID != tokens[0].ID {
+		t.Errorf("got token id %d; want %d", s.TokenID, tokens[0].ID)
+	}
+}
+
+func TestSearchPageBounds(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	testPageBounds(t, ts, "/search?q=hello&page=")
+}
+
+func TestTagPageBounds(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	testPageBounds(t, ts, "/tag/go?page=")
+}
+
+func TestCleanTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{name: "None", tags: nil, want: nil},
+		{name: "Blank", tags: []string{"", " , "}, want: nil},
+		{name: "One box", tags: []string{"sql, k8s bash"}, want: []string{"sql", "k8s", "bash"}},
+		{name: "Array", tags: []string{"sql", "k8s"}, want: []string{"sql", "k8s"}},
+		{name: "Lower case", tags: []string{"Go", "C++"}, want: []string{"go", "c++"}},
+		{name: "Duplicates", tags: []string{"go, GO", "sql go"}, want: []string{"go", "sql"}},
+		{name: "Tabs and newlines", tags: []string{"a\tb\nc"}, want: []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanTags(tt.tags); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippetViewLines(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+
+	_, err := app.snippets.Insert(t.Context(), models.SnippetInput{
+		Title:   "An old silent pond",
+		Content: "An old silent pond...\nA frog jumps into the pond,\nsplash! Silence again.\n",
+		Expires: 7,
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{name: "Whole snippet", urlPath: "/snippet/view/1", wantCode: http.StatusOK, wantBody: "A frog jumps"},
+		{name: "Range", urlPath: "/snippet/view/1?lines=2-3", wantCode: http.StatusOK, wantBody: "Lines 2-3 of 3"},
+		{name: "Single line", urlPath: "/snippet/view/1?lines=2", wantCode: http.StatusOK, wantBody: "Lines 2 of 3"},
+		{name: "Past the end", urlPath: "/snippet/view/1?lines=3-99", wantCode: http.StatusOK, wantBody: "Lines 3 of 3"},
+		{name: "After the end", urlPath: "/snippet/view/1?lines=4", wantCode: http.StatusNotFound},
+		{name: "Backwards", urlPath: "/snippet/view/1?lines=3-2", wantCode: http.StatusBadRequest},
+		{name: "Malformed", urlPath: "/snippet/view/1?lines=L2-L3", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			if code != tt.wantCode {
+				t.Errorf("got status %d; want %d", code, tt.wantCode)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("body doesn't contain %q", tt.wantBody)
+			}
+		})
+	}
+}
@@ -6,20 +6,34 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/form/v4"
+	"github.com/justinas/nosurf"
+	"snippetbox.vishalborana2407.net/internal/models"
 )
 
 // The serverError helper writes a log entry at Error level (including the request
 // method and URI as attributes), then sends a generic 500 Internal Server Error
 // response to the user.
+// If the error is a query timeout, the database is slow rather than broken, so
+// we send a 503 Service Unavailable instead and ask the client to retry.
 func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
 	var (
 		method = r.Method
 		uri    = r.URL.RequestURI()
 		trace  = debug.Stack()
 	)
+
+	if errors.Is(err, models.ErrTimeout) {
+		app.logger.Warn(err.Error(), "method", method, "uri", uri)
+		w.Header().Set("Retry-After", "5")
+		app.clientError(w, http.StatusServiceUnavailable)
+		return
+	}
+
 	app.logger.Error(err.Error(), "method", method, "uri", uri, "stack_trace", trace)
 	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 }
@@ -73,11 +87,100 @@ func (app *application) render(w http.ResponseWriter, r *http.Request, status in
 	}
 }
 
-// newTemplateData creates a new templateData struct intialized with the current year.
+// newTemplateData creates a new templateData struct intialized with the current year,
+// whether the user is logged in and the CSRF token for any forms on the page.
 func (app *application) newTemplateData(r *http.Request) templateData {
 	return templateData{
-		CurrentYear: time.Now().Year(),
+		CurrentYear:     time.Now().Year(),
+		IsAuthenticated: app.isAuthenticated(r),
+		UserID:          app.authenticatedUserID(r),
+		CSRFToken:       nosurf.Token(r), // Add the CSRF token.
+		Flash:           app.popFlash(r),
+	}
+}
+
+// putFlash stores a flash message in the session, to be shown on the next
+// page rendered for this user (usually the one we're about to redirect to).
+func (app *application) putFlash(r *http.Request, level, message string) {
+	app.sessionManager.Put(r.Context(), "flash", flash{Level: level, Message: message})
+}
+
+// popFlash retrieves the flash message from the session and removes it, so it
+// is only ever shown once. It returns nil if there isn't one.
+func (app *application) popFlash(r *http.Request) *flash {
+	f, ok := app.sessionManager.Pop(r.Context(), "flash").(flash)
+	if !ok {
+		return nil
+	}
+
+	return &f
+}
+
+// isAuthenticated returns true if the current request is from an authenticated
+// user, otherwise false. The value is set by the authenticate middleware.
+func (app *application) isAuthenticated(r *http.Request) bool {
+	isAuthenticated, ok := r.Context().Value(isAuthenticatedContextKey).(bool)
+	if !ok {
+		return false
+	}
+
+	return isAuthenticated
+}
+
+// authenticatedUserID returns the id of the logged-in user making the
+// request, or 0 if the request isn't authenticated.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	id, _ := r.Context().Value(userIDContextKey).(int)
+	return id
+}
+
+// authenticatedTokenID returns the id of the API token used to authenticate
+// the request, or 0 if it wasn't made with a token.
+func (app *application) authenticatedTokenID(r *http.Request) int {
+	id, _ := r.Context().Value(tokenIDContextKey).(int)
+	return id
+}
+
+// logSnippetCreated records who created a snippet, and which API token they
+// used if any, so that a misbehaving script can be tracked down to its
+// token.
+func (app *application) logSnippetCreated(r *http.Request, snippetID int) {
+	app.logger.Info("Snippet created", "snippet_id", snippetID,
+		"user_id", app.authenticatedUserID(r), "token_id", app.authenticatedTokenID(r))
+}
+
+// isAPIRequest reports whether r is for the JSON API, which wants its errors
+// as JSON too.
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+// normalizeEmail trims and lower-cases an email address before it's stored
+// or looked up. MySQL compares emails case-insensitively anyway, but SQLite,
+// Postgres and the memory store don't, and "Alice@example.com" and
+// "alice@example.com" shouldn't be two different accounts.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// maxPage is the highest ?page= accepted. It keeps (page-1)*pageSize well
+// away from overflowing, and OFFSET queries that deep are slow anyway; the
+// keyset paginated /snippets page is the way to go through everything.
+const maxPage = 10000
+
+// pageNumber reads the ?page= query string parameter of a paged list. It's 1
+// if there isn't one, and ok is false if it isn't a number from 1 to maxPage.
+func pageNumber(r *http.Request) (page int, ok bool) {
+	v := r.URL.Query().Get("page")
+	if v == "" {
+		return 1, true
+	}
+
+	page, err := strconv.Atoi(v)
+	if err != nil || page < 1 || page > maxPage {
+		return 0, false
 	}
+	return page, true
 }
 
 // helper utiity for form parsing + decoding and checking for errors
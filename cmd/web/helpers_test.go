@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPageNumber(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantPage int
+		wantOK   bool
+	}{
+		{query: "", wantPage: 1, wantOK: true},
+		{query: "?page=1", wantPage: 1, wantOK: true},
+		{query: "?page=42", wantPage: 42, wantOK: true},
+		{query: "?page=10000", wantPage: maxPage, wantOK: true},
+		{query: "?page=10001", wantOK: false},
+		{query: "?page=9223372036854775807", wantOK: false},
+		{query: "?page=0", wantOK: false},
+		{query: "?page=-3", wantOK: false},
+		{query: "?page=1.5", wantOK: false},
+		{query: "?page=one", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/search"+tt.query, nil)
+
+			page, ok := pageNumber(r)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %t; want %t", ok, tt.wantOK)
+			}
+			if ok && page != tt.wantPage {
+				t.Errorf("got page %d; want %d", page, tt.wantPage)
+			}
+		})
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{email: "alice@example.com", want: "alice@example.com"},
+		{email: "Alice@Example.COM", want: "alice@example.com"},
+		{email: "  bob@example.com\n", want: "bob@example.com"},
+		{email: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			if got := normalizeEmail(tt.email); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
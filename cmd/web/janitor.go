@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// startJanitor starts a background goroutine which deletes expired snippets
+// every interval, batchSize rows at a time. It runs until ctx is cancelled;
+// the returned channel is closed once the goroutine has finished, so callers
+// can wait for a purge in progress to stop before closing the database.
+func (app *application) startJanitor(ctx context.Context, interval time.Duration, batchSize int) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		app.logger.Info("Janitor started", "interval", interval, "batch_size", batchSize)
+
+		for {
+			select {
+			case <-ctx.Done():
+				app.logger.Info("Janitor stopped")
+				return
+			case <-ticker.C:
+				app.purgeExpired(ctx, batchSize)
+			}
+		}
+	}()
+
+	return done
+}
+
+// purgeExpired deletes expired snippets in batches until there are none left
+// (or ctx is cancelled) and logs how many were removed.
+func (app *application) purgeExpired(ctx context.Context, batchSize int) {
+	total := 0
+
+	for {
+		n, err := app.snippets.DeleteExpired(ctx, batchSize)
+		total += n
+		if err != nil {
+			// a cancelled context just means we're shutting down
+			if ctx.Err() == nil {
+				app.logger.Error("Janitor failed to delete expired snippets", "error", err.Error(), "deleted", total)
+			}
+			return
+		}
+
+		// a short batch means there's nothing left to delete
+		if n < batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		app.logger.Info("Janitor deleted expired snippets", "deleted", total)
+	}
+}
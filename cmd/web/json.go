@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"snippetbox.vishalborana2407.net/internal/models"
+)
+
+// maxJSONBytes limits the size of API request bodies.
+const maxJSONBytes = 1 << 20
+
+// envelope wraps every JSON response in a top-level object, e.g.
+// {"snippet": {...}} or {"error": {...}}, so that fields can be added later
+// without breaking clients.
+type envelope map[string]any
+
+// apiErrorBody is the value of the "error" key in an error response. Fields
+// holds the per-field messages of a failed validation.
+type apiErrorBody struct {
+	Status  int               `json:"status"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// writeJSON sends data as an indented JSON response with the given status.
+func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope) error {
+	js, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(js, '\n'))
+
+	return nil
+}
+
+// errJSONContentType is returned by readJSON when the request isn't marked as
+// JSON.
+var errJSONContentType = errors.New("Content-Type must be application/json")
+
+// readJSON decodes a JSON request body into dst. The body must be a single
+// JSON object of at most maxJSONBytes with no fields that dst doesn't have.
+// The errors it returns are safe to show to the client.
+//
+// Insisting on Content-Type: application/json also protects the API against
+// CSRF: a browser won't send that cross-origin without a CORS preflight,
+// which we never approve.
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return errJSONContentType
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(dst)
+	if err != nil {
+		var (
+			syntaxError    *json.SyntaxError
+			typeError      *json.UnmarshalTypeError
+			maxBytesError  *http.MaxBytesError
+			invalidUnmarsh *json.InvalidUnmarshalError
+		)
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains badly-formed JSON")
+		case errors.As(err, &typeError):
+			if typeError.Field != "" {
+				return fmt.Errorf("body contains the wrong type for field %q", typeError.Field)
+			}
+			return fmt.Errorf("body contains the wrong type (at character %d)", typeError.Offset)
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return fmt.Errorf("body contains unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		case errors.As(err, &maxBytesError):
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		case errors.As(err, &invalidUnmarsh):
+			// a bug in our code rather than a bad request
+			panic(err)
+		default:
+			return err
+		}
+	}
+
+	// Anything after the first JSON value is a mistake too.
+	if dec.More() {
+		return errors.New("body must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+// apiError sends a JSON error envelope with the given status and message. If
+// the response can't be written there's nothing more we can tell the client,
+// so the error is only logged.
+func (app *application) apiError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	app.apiErrorBody(w, r, apiErrorBody{Status: status, Message: message})
+}
+
+func (app *application) apiErrorBody(w http.ResponseWriter, r *http.Request, body apiErrorBody) {
+	err := app.writeJSON(w, body.Status, envelope{"error": body})
+	if err != nil {
+		app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+	}
+}
+
+// apiServerError is the JSON version of serverError: it logs err and sends a
+// 500, or a 503 with Retry-After if the database timed out.
+func (app *application) apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, models.ErrTimeout) {
+		app.logger.Warn(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+		w.Header().Set("Retry-After", "5")
+		app.apiError(w, r, http.StatusServiceUnavailable, "the server is busy, please try again later")
+		return
+	}
+
+	app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+	app.apiError(w, r, http.StatusInternalServerError, "the server encountered a problem and could not process your request")
+}
+
+// apiNotFound sends a JSON 404. It's also the handler for unknown /api/ URLs.
+func (app *application) apiNotFound(w http.ResponseWriter, r *http.Request) {
+	app.apiError(w, r, http.StatusNotFound, "the requested resource could not be found")
+}
+
+// apiFailedValidation sends a 422 listing the field errors found by a
+// validator.
+func (app *application) apiFailedValidation(w http.ResponseWriter, r *http.Request, fieldErrors map[string]string) {
+	app.apiErrorBody(w, r, apiErrorBody{
+		Status:  http.StatusUnprocessableEntity,
+		Message: "validation failed",
+		Fields:  fieldErrors,
+	})
+}
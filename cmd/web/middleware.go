@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/justinas/nosurf"
+	"snippetbox.vishalborana2407.net/internal/models"
 )
 
 // middleware to add common headers
@@ -65,3 +71,199 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// requireAuthentication redirects users who aren't logged in to the login page,
+// and stops pages that require authentication from being cached by the browser.
+func (app *application) requireAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// If the user is not authenticated, redirect them to the login page and
+		// return from the middleware chain so that no subsequent handlers in
+		// the chain are executed.
+		if !app.isAuthenticated(r) {
+			app.putFlash(r, flashWarning, "Please log in to continue.")
+			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+			return
+		}
+
+		// Otherwise set the "Cache-Control: no-store" header so that pages
+		// which require authentication are not stored in the user's browser
+		// cache (or other intermediary cache).
+		w.Header().Add("Cache-Control", "no-store")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireSession only lets through requests authenticated by a logged-in
+// session, for the pages which manage API tokens. Otherwise a token could
+// mint itself a successor which never expires, or revoke its owner's other
+// tokens. It goes after requireAuthentication.
+func (app *application) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.authenticatedTokenID(r) != 0 {
+			app.tokenError(w, r, http.StatusForbidden, "insufficient_scope", "API tokens can't be used to manage API tokens; log in instead")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAPIAuthentication is the API version of requireAuthentication: rather
+// than redirecting to the login page it sends a 401 Unauthorized JSON error.
+func (app *application) requireAPIAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			app.apiError(w, r, http.StatusUnauthorized, "you must be authenticated to access this resource")
+			return
+		}
+
+		w.Header().Add("Cache-Control", "no-store")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticateToken authenticates requests which carry an API token in an
+// "Authorization: Bearer <token>" header. Requests without an Authorization
+// header are passed on untouched, for authenticate() to check the session.
+// A token only works for what its scopes allow: read for GET, HEAD and
+// OPTIONS requests, write for everything else.
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			app.tokenError(w, r, http.StatusUnauthorized, "invalid_request", "the Authorization header must look like: Bearer <token>")
+			return
+		}
+
+		t, err := app.tokens.Authenticate(r.Context(), strings.TrimSpace(token))
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrInvalidToken):
+				app.tokenError(w, r, http.StatusUnauthorized, "invalid_token", "invalid or expired API token")
+			case isAPIRequest(r):
+				app.apiServerError(w, r, err)
+			default:
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		scope := models.ScopeWrite
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			scope = models.ScopeRead
+		}
+		if !t.HasScope(scope) {
+			app.tokenError(w, r, http.StatusForbidden, "insufficient_scope", fmt.Sprintf("this API token doesn't have the %q scope", scope))
+			return
+		}
+
+		// The request now counts as coming from the token's owner, exactly
+		// as if they'd logged in.
+		ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+		ctx = context.WithValue(ctx, userIDContextKey, t.UserID)
+		ctx = context.WithValue(ctx, tokenIDContextKey, t.ID)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// tokenError rejects a request with a bad API token. The WWW-Authenticate
+// header carries the error code from RFC 6750; the body is JSON for the API
+// and plain text everywhere else.
+func (app *application) tokenError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer error=%q", code))
+
+	if isAPIRequest(r) {
+		app.apiError(w, r, status, message)
+		return
+	}
+	http.Error(w, message, status)
+}
+
+// authenticate checks the user id stored in the session against the database,
+// and records in the request context whether the request comes from an
+// authenticated user.
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Requests with a valid API token were authenticated by
+		// authenticateToken() already.
+		if app.isAuthenticated(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Retrieve the authenticatedUserID value from the session using the
+		// GetInt() method. This will return the zero value for an int (0) if no
+		// "authenticatedUserID" value is in the session -- in which case we
+		// call the next handler in the chain as normal and return.
+		id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+		if id == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Otherwise, we check to see if a user with that ID exists in our
+		// database.
+		exists, err := app.users.Exists(r.Context(), id)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		// If a matching user is found, we know that the request is
+		// coming from an authenticated user who exists in our database. We
+		// create a new copy of the request (with an isAuthenticatedContextKey
+		// value of true and the user's id in the request context) and assign
+		// it to r.
+		if exists {
+			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+			ctx = context.WithValue(ctx, userIDContextKey, id)
+			r = r.WithContext(ctx)
+		}
+
+		// Call the next handler in the chain.
+		next.ServeHTTP(w, r)
+	})
+}
+
+// noSurf adds CSRF protection to every state-changing (non-GET/HEAD/OPTIONS/
+// TRACE) request. It uses a customized CSRF cookie with the Secure, Path and
+// HttpOnly attributes set the same way as our session cookie. Requests which
+// fail the check get a 400 Bad Request page.
+func (app *application) noSurf(next http.Handler) http.Handler {
+	csrfHandler := nosurf.New(next)
+	csrfHandler.SetBaseCookie(http.Cookie{
+		HttpOnly: true,
+		Path:     "/",
+		Secure:   app.sessionManager.Cookie.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	csrfHandler.SetFailureHandler(http.HandlerFunc(app.csrfFailure))
+
+	// Scripts using an API token have no CSRF token to send. That's safe
+	// because browsers never add an Authorization header by themselves, and
+	// authenticateToken() rejects any request whose header isn't a valid
+	// token.
+	csrfHandler.ExemptFunc(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") != ""
+	})
+
+	// nosurf compares the Origin (or Referer) header against our own origin,
+	// and assumes that's https:// unless told otherwise. We're on HTTPS if the
+	// request came in over TLS, or if Secure cookies were forced because TLS
+	// is terminated by a proxy in front of us.
+	csrfHandler.SetIsTLSFunc(func(r *http.Request) bool {
+		return r.TLS != nil || app.sessionManager.Cookie.Secure
+	})
+
+	return csrfHandler
+}
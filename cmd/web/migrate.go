@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"snippetbox.vishalborana2407.net/internal/migrations"
+)
+
+// runMigrate runs one of the migrate subcommands ("up", "down" or "status")
+// and logs what happened.
+func runMigrate(logger *slog.Logger, migrator *migrations.Migrator, command string) error {
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		for _, mig := range applied {
+			logger.Info("Applied migration", "version", mig.Version, "name", mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			logger.Info("Database schema is up to date")
+		}
+
+	case "down":
+		mig, err := migrator.Down()
+		if errors.Is(err, migrations.ErrNoMigrations) {
+			logger.Info("No migrations to roll back")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		logger.Info("Rolled back migration", "version", mig.Version, "name", mig.Name)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			if st.Applied {
+				logger.Info("Migration applied", "version", st.Version, "name", st.Name, "applied_at", st.AppliedAt)
+			} else {
+				logger.Info("Migration pending", "version", st.Version, "name", st.Name)
+			}
+		}
+
+	default:
+		return fmt.Errorf("unknown migrate command %q (want up, down or status)", command)
+	}
+
+	return nil
+}
@@ -1,7 +1,10 @@
 package main
 
-import "net/http"
-import "github.com/justinas/alice"
+import (
+	"net/http"
+
+	"github.com/justinas/alice"
+)
 
 // The routes() method returns a servemux containing our application routes.
 func (app *application) routes() http.Handler {
@@ -23,17 +26,67 @@ func (app *application) routes() http.Handler {
 	*/
 	mux.Handle("GET /static/", http.StripPrefix("/static/", fileServer))
 
+	// Raw snippets are for scripts, which don't need a session or CSRF
+	// protection, so they're served without the dynamic middleware.
+	mux.HandleFunc("GET /snippet/raw/{id}", app.snippetRaw)
+
 	// Register handlers
 	// Swap the route declarations to use the application struct's methods as the
 	// handler functions.
 
+	// Create a new middleware chain containing the middleware specific to our
+	// dynamic application routes. LoadAndSave() loads and saves the session
+	// data for each request, noSurf() rejects state-changing requests without
+	// a valid CSRF token, and authenticate() works out whether the request
+	// comes from a logged-in user. authenticateToken() lets scripts use an
+	// API token instead of logging in.
+	dynamic := alice.New(app.sessionManager.LoadAndSave, app.noSurf, app.authenticateToken, app.authenticate)
+
 	// Register GET routes
-	mux.HandleFunc("GET /{$}", app.home)
-	mux.HandleFunc("GET /snippet/view/{id}", app.snippetView)
-	mux.HandleFunc("GET /snippet/create", app.snippetCreate)
+	mux.Handle("GET /{$}", dynamic.ThenFunc(app.home))
+	mux.Handle("GET /snippet/view/{id}", dynamic.ThenFunc(app.snippetView))
+	mux.Handle("GET /snippet/view/{id}/history", dynamic.ThenFunc(app.snippetHistory))
+	mux.Handle("GET /snippet/view/{id}/diff", dynamic.ThenFunc(app.snippetDiff))
+	mux.Handle("GET /snippets", dynamic.ThenFunc(app.snippetsBrowse))
+	mux.Handle("GET /search", dynamic.ThenFunc(app.search))
+	mux.Handle("GET /tag/{name}", dynamic.ThenFunc(app.tagView))
+	mux.Handle("GET /user/signup", dynamic.ThenFunc(app.userSignup))
+	mux.Handle("GET /user/login", dynamic.ThenFunc(app.userLogin))
 
 	// Register POST routes
-	mux.HandleFunc("POST /snippet/create", app.snippetCreatePost)
+	mux.Handle("POST /user/signup", dynamic.ThenFunc(app.userSignupPost))
+	mux.Handle("POST /user/login", dynamic.ThenFunc(app.userLoginPost))
+
+	// Protected (authenticated-only) application routes, using a new "protected"
+	// middleware chain which includes the requireAuthentication middleware.
+	protected := dynamic.Append(app.requireAuthentication)
+
+	mux.Handle("GET /snippet/create", protected.ThenFunc(app.snippetCreate))
+	mux.Handle("POST /snippet/create", protected.ThenFunc(app.snippetCreatePost))
+	mux.Handle("GET /snippet/edit/{id}", protected.ThenFunc(app.snippetEdit))
+	mux.Handle("POST /snippet/edit/{id}", protected.ThenFunc(app.snippetEditPost))
+	mux.Handle("GET /snippets/mine", protected.ThenFunc(app.snippetsMine))
+	mux.Handle("POST /snippet/delete/{id}", protected.ThenFunc(app.snippetDeletePost))
+	mux.Handle("POST /user/logout", protected.ThenFunc(app.userLogoutPost))
+
+	// Managing API tokens needs a real login, not one of the tokens.
+	session := protected.Append(app.requireSession)
+
+	mux.Handle("GET /account/tokens", session.ThenFunc(app.accountTokens))
+	mux.Handle("POST /account/tokens", session.ThenFunc(app.accountTokensPost))
+	mux.Handle("POST /account/tokens/{id}/revoke", session.ThenFunc(app.accountTokenRevokePost))
+
+	// The JSON API under /api/v1. It uses the same session as the web pages,
+	// but not noSurf(): API clients don't have a CSRF token, and readJSON()
+	// only accepts requests which a browser won't send cross-origin.
+	api := alice.New(app.sessionManager.LoadAndSave, app.authenticateToken, app.authenticate)
+
+	mux.Handle("GET /api/v1/snippets", api.ThenFunc(app.apiSnippetList))
+	mux.Handle("GET /api/v1/snippets/{id}", api.ThenFunc(app.apiSnippetGet))
+	mux.Handle("POST /api/v1/snippets", api.Append(app.requireAPIAuthentication).ThenFunc(app.apiSnippetCreate))
+	// Anything else under /api/ gets a JSON 404 rather than the plain text
+	// one from the servemux.
+	mux.Handle("/api/", api.ThenFunc(app.apiNotFound))
 
 	// create standard middleware chain that will be used by all routes
 	standardChain := alice.New(app.recoverPanic, app.logRequest, commonHeaders)
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// newServer returns an http.Server for handler. Setting explicit timeouts
+// stops slow or idle clients from holding on to connections forever.
+func (app *application) newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:    addr,
+		Handler: handler,
+		// send the server's own errors (like TLS handshake failures) to our
+		// structured logger rather than the standard logger
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+}
+
+// serve runs the given servers until the process receives SIGINT or SIGTERM
+// (or one of them fails), then stops accepting new connections and waits up
+// to drainTimeout for in-flight requests to finish. A server with a TLSConfig
+// serves HTTPS using the certificates in it. It returns nil after a clean
+// shutdown.
+func (app *application) serve(servers []*http.Server, drainTimeout time.Duration) error {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	// listenErr receives the result of each ListenAndServe() call.
+	listenErr := make(chan error, len(servers))
+
+	for _, srv := range servers {
+		go func() {
+			if srv.TLSConfig != nil {
+				app.logger.Info("Starting server on", "addr", srv.Addr, "tls", true)
+				// the certificate and key are already in srv.TLSConfig
+				listenErr <- srv.ListenAndServeTLS("", "")
+				return
+			}
+
+			app.logger.Info("Starting server on", "addr", srv.Addr, "tls", false)
+			listenErr <- srv.ListenAndServe()
+		}()
+	}
+
+	// Block until we're told to stop or a server fails to start.
+	var err error
+	select {
+	case sig := <-quit:
+		app.logger.Info("Shutting down server", "signal", sig.String(), "drain_timeout", drainTimeout)
+	case err = <-listenErr:
+		app.logger.Info("Shutting down server", "error", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	// Shutdown() closes the listeners, then waits for active requests to
+	// complete (or for ctx to expire).
+	for _, srv := range servers {
+		shutdownErr := srv.Shutdown(ctx)
+		if shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
+
+	// ListenAndServe() returns http.ErrServerClosed once Shutdown() is called,
+	// which is exactly what we expect here.
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("Stopped server")
+
+	return nil
+}
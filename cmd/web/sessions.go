@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alexedwards/scs/mysqlstore"
+	"github.com/alexedwards/scs/postgresstore"
+	"github.com/alexedwards/scs/sqlite3store"
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexedwards/scs/v2/memstore"
+)
+
+// sessionConfig holds the session settings from the command-line flags.
+type sessionConfig struct {
+	store        string // "auto", "database" or "memory"
+	lifetime     time.Duration
+	idleTimeout  time.Duration
+	cookieSecure bool
+	sameSite     string // "lax" or "strict"
+}
+
+// cleanupStore is implemented by the scs stores which delete expired sessions
+// in a background goroutine.
+type cleanupStore interface {
+	scs.Store
+	StopCleanup()
+}
+
+// newSessionManager returns a session manager using the store picked in cfg.
+// The "database" store keeps sessions in the sessions table of db (created by
+// the migrations), so they survive restarts and are shared between instances;
+// "auto" picks it whenever we have a database. The returned function stops the
+// store's cleanup goroutine and must be called before db is closed.
+func newSessionManager(cfg sessionConfig, driver string, db *sql.DB) (*scs.SessionManager, func(), error) {
+	storeName := cfg.store
+	if storeName == "auto" {
+		storeName = "memory"
+		if db != nil {
+			storeName = "database"
+		}
+	}
+
+	var store cleanupStore
+
+	switch storeName {
+	case "database":
+		if db == nil {
+			return nil, nil, fmt.Errorf("the %s db driver can't hold sessions; use -session-store=memory", driver)
+		}
+
+		switch driver {
+		case "mysql":
+			store = mysqlstore.New(db)
+		case "postgres":
+			store = postgresstore.New(db)
+		case "sqlite":
+			store = sqlite3store.New(db)
+		}
+	case "memory":
+		store = memstore.New()
+	default:
+		return nil, nil, fmt.Errorf("unknown session store %q", cfg.store)
+	}
+
+	// Use the scs.New() function to initialize a new session manager. Then we
+	// configure it to use our store and set the lifetime (the absolute
+	// maximum age of a session) and idle timeout (how long a session lasts
+	// without any activity; zero means it never idles out).
+	sessionManager := scs.New()
+	sessionManager.Store = store
+	sessionManager.Lifetime = cfg.lifetime
+	sessionManager.IdleTimeout = cfg.idleTimeout
+
+	// HttpOnly stops JavaScript reading the session cookie. Secure makes sure
+	// the cookie is only sent over HTTPS, and SameSite stops browsers sending it
+	// along with (most) cross-site requests.
+	sessionManager.Cookie.HttpOnly = true
+	sessionManager.Cookie.Secure = cfg.cookieSecure
+
+	switch cfg.sameSite {
+	case "lax":
+		sessionManager.Cookie.SameSite = http.SameSiteLaxMode
+	case "strict":
+		sessionManager.Cookie.SameSite = http.SameSiteStrictMode
+	default:
+		store.StopCleanup()
+		return nil, nil, fmt.Errorf("unknown SameSite mode %q (want lax or strict)", cfg.sameSite)
+	}
+
+	return sessionManager, store.StopCleanup, nil
+}
@@ -2,9 +2,15 @@ package main
 
 import (
 	"html/template"
+	"net/http"
 	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"snippetbox.vishalborana2407.net/internal/diff"
+	"snippetbox.vishalborana2407.net/internal/highlight"
 	"snippetbox.vishalborana2407.net/internal/models"
 )
 
@@ -16,10 +22,90 @@ import (
 // lowercase starting  = private (not accessible outside of this package)
 // uppercase starting = public (accessible outside of this package)
 type templateData struct {
-	Snippet     models.Snippet
-	Snippets    []models.Snippet
-	CurrentYear int
-	Form        any
+	Snippet         models.Snippet
+	Snippets        []models.Snippet
+	CurrentYear     int
+	Form            any
+	IsAuthenticated bool
+	UserID          int    // id of the logged-in user, 0 if there isn't one
+	CSRFToken       string // hidden field value for every form
+	Error           errorPage
+	Flash           *flash // nil when there's no flash message to show
+	Pagination      pagination
+	Revisions       []models.Revision
+	Diff            diffPage
+	Tokens          []models.Token
+	NewToken        string // a token that was just created, shown only once
+	TokenName       string // the API token Snippet was created with, for its owner
+	Search          searchPage
+	Browse          browsePage
+	Tag             string        // the tag whose snippets are listed
+	Code            template.HTML // the highlighted or rendered content of Snippet
+	ShowSource      bool          // Code is the highlighted source, not rendered Markdown
+	Lines           linesPage
+}
+
+// linesPage describes the slice of a snippet shown by view.tmpl for a
+// ?lines= query. Total is the number of lines in the whole snippet; the zero
+// linesPage means the whole snippet is shown.
+type linesPage struct {
+	highlight.Range
+	Total int
+}
+
+// browsePage holds the links to the neighbouring pages of the keyset
+// paginated browse.tmpl page: the cursors of its first and last snippets,
+// and whether there's anything before or after them.
+type browsePage struct {
+	Prev, Next       string
+	HasPrev, HasNext bool
+}
+
+// searchPage holds the query shown on the search.tmpl page (and in the search
+// box in the nav), and the terms it was split into for highlighting.
+type searchPage struct {
+	Query string
+	Terms []string
+}
+
+// diffPage holds what the diff.tmpl page shows: two revisions of a snippet
+// and the differences between their content, laid out for the chosen Mode.
+type diffPage struct {
+	From, To models.Revision
+	Mode     string // "unified" or "split"
+	Hunks    []diff.Hunk
+	Rows     []diff.Row
+}
+
+// pagination describes where a paged list (like "my snippets") is up to.
+// Pages are numbered from 1.
+type pagination struct {
+	Page    int
+	HasNext bool
+}
+
+// Prev and Next return the neighbouring page numbers, for building links.
+func (p pagination) Prev() int { return p.Page - 1 }
+func (p pagination) Next() int { return p.Page + 1 }
+
+// flash is a one-shot message stored in the session by a handler before it
+// redirects, and shown on the next page the user sees. Level is one of
+// flashSuccess, flashWarning or flashError and picks how it's styled.
+type flash struct {
+	Level   string
+	Message string
+}
+
+const (
+	flashSuccess = "success"
+	flashWarning = "warning"
+	flashError   = "error"
+)
+
+// errorPage holds what the error.tmpl page shows.
+type errorPage struct {
+	Status  int
+	Message string
 }
 
 // helper function to format a time.Time object as a human-readable date
@@ -27,9 +113,125 @@ func humanDate(t time.Time) string {
 	return t.Format("02 Jan 2006 at 15:04")
 }
 
+// orNever formats t like humanDate, or as "Never" if it's the zero time.
+func orNever(t time.Time) string {
+	if t.IsZero() {
+		return "Never"
+	}
+	return humanDate(t)
+}
+
+// excerptLength is roughly how many characters of a snippet's content the
+// search results show.
+const excerptLength = 200
+
+// excerpt returns about excerptLength characters of content, starting a
+// little before the first word which matches one of terms, so search results
+// show why a snippet matched. Cut-off ends are marked with an ellipsis.
+func excerpt(content string, terms []string) string {
+	// collapse runs of whitespace, so line breaks don't waste the space
+	flat := strings.Join(strings.Fields(content), " ")
+	text := []rune(flat)
+	if len(text) <= excerptLength {
+		return flat
+	}
+
+	start := 0
+	if i := firstMatch(flat, terms); i > 0 {
+		// i is a byte offset; count the runes before it
+		start = max(utf8.RuneCountInString(flat[:i])-excerptLength/4, 0)
+	}
+	start = min(start, len(text)-excerptLength)
+	// don't start halfway through a word
+	for start > 0 && start < len(text) && text[start-1] != ' ' {
+		start++
+	}
+	end := min(start+excerptLength, len(text))
+
+	out := string(text[start:end])
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(text) {
+		out += "…"
+	}
+	return out
+}
+
+// firstMatch returns the byte offset of the first word in text which starts
+// with one of terms, or -1 if there isn't one.
+func firstMatch(text string, terms []string) int {
+	for _, w := range words(text) {
+		if matchesTerm(text[w[0]:w[1]], terms) {
+			return w[0]
+		}
+	}
+	return -1
+}
+
+// markTerms HTML-escapes text and wraps each word starting with one of terms
+// in a <mark> element.
+func markTerms(text string, terms []string) template.HTML {
+	var b strings.Builder
+	last := 0
+	for _, w := range words(text) {
+		word := text[w[0]:w[1]]
+		if !matchesTerm(word, terms) {
+			continue
+		}
+		b.WriteString(template.HTMLEscapeString(text[last:w[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(template.HTMLEscapeString(word))
+		b.WriteString("</mark>")
+		last = w[1]
+	}
+	b.WriteString(template.HTMLEscapeString(text[last:]))
+
+	return template.HTML(b.String())
+}
+
+// words returns the start and end byte offsets of each run of letters and
+// digits in text, which is how models.SearchTerms() splits up a query.
+func words(text string) [][2]int {
+	var (
+		spans [][2]int
+		start = -1
+	)
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+// matchesTerm reports whether word starts with any of terms, ignoring case.
+func matchesTerm(word string, terms []string) bool {
+	word = strings.ToLower(word)
+	for _, t := range terms {
+		if strings.HasPrefix(word, t) {
+			return true
+		}
+	}
+	return false
+}
+
 // initialize a template.Funcmap value and store it in a global variabe
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":  humanDate,
+	"orNever":    orNever,
+	"statusText": http.StatusText,
+	"add":        func(a, b int) int { return a + b },
+	"excerpt":    excerpt,
+	"markTerms":  markTerms,
 }
 
 // create a new template cache that will hold all the templates
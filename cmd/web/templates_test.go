@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("lorem ipsum ", 30) + "needle " + strings.Repeat("dolor sit ", 30)
+
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{name: "Short", text: "func main() {}", terms: []string{"main"}, want: "func main() {}"},
+		{name: "Whitespace", text: "a\n\n\tb   c", terms: nil, want: "a b c"},
+		{name: "Empty", text: "", terms: []string{"x"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := excerpt(tt.text, tt.terms); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("No match", func(t *testing.T) {
+		got := excerpt(long, []string{"absent"})
+		if !strings.HasPrefix(got, "lorem ipsum") || !strings.HasSuffix(got, "…") {
+			t.Errorf("got %q; want the start of the text", got)
+		}
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		got := excerpt(long, []string{"need"})
+		if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+			t.Errorf("got %q; want both ends cut off", got)
+		}
+		if !strings.Contains(got, "needle") {
+			t.Errorf("got %q; want it to contain the match", got)
+		}
+		// it starts at the beginning of a word
+		if w := strings.TrimPrefix(got, "…"); !strings.HasPrefix(w, "lorem") && !strings.HasPrefix(w, "ipsum") {
+			t.Errorf("got %q; want it to start at a word", got)
+		}
+		if n := utf8.RuneCountInString(got); n > excerptLength+2 {
+			t.Errorf("got %d runes; want at most %d", n, excerptLength+2)
+		}
+	})
+
+	t.Run("Match near the end", func(t *testing.T) {
+		got := excerpt(long+" haystack", []string{"haystack"})
+		if !strings.HasSuffix(got, "haystack") {
+			t.Errorf("got %q; want it to run to the end", got)
+		}
+	})
+}
+
+func TestMarkTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{name: "No terms", text: "a <b>", terms: nil, want: "a &lt;b&gt;"},
+		{name: "Word", text: "go to the go store", terms: []string{"go"}, want: "<mark>go</mark> to the <mark>go</mark> store"},
+		{name: "Prefix", text: "Golang rules", terms: []string{"go"}, want: "<mark>Golang</mark> rules"},
+		{name: "Not mid-word", text: "ergo", terms: []string{"go"}, want: "ergo"},
+		{name: "Several terms", text: "SELECT * FROM users", terms: []string{"select", "user"}, want: "<mark>SELECT</mark> * FROM <mark>users</mark>"},
+		{name: "Escaped", text: "<script>alert(1)</script>", terms: []string{"alert"}, want: "&lt;script&gt;<mark>alert</mark>(1)&lt;/script&gt;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(markTerms(tt.text, tt.terms)); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
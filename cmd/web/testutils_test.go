@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-playground/form/v4"
+
+	"snippetbox.vishalborana2407.net/internal/models"
+)
+
+// TestMain runs the tests from the repository root, where the server itself
+// runs from, so newTemplateCache() finds ./ui/html.
+func TestMain(m *testing.M) {
+	err := os.Chdir("../..")
+	if err != nil {
+		panic(err)
+	}
+
+	gob.Register(flash{})
+
+	os.Exit(m.Run())
+}
+
+// newTestApplication returns an application backed by the memory models, so
+// the handlers can be tested without a database.
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	templateCache, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// scs.New() keeps sessions in memory.
+	sessionManager := scs.New()
+	sessionManager.Lifetime = 12 * time.Hour
+
+	users := &models.MemoryUserModel{}
+
+	return &application{
+		logger:         slog.New(slog.DiscardHandler),
+		snippets:       &models.MemorySnippetModel{Users: users},
+		users:          users,
+		tokens:         &models.MemoryTokenModel{},
+		templateCache:  templateCache,
+		formDecoder:    form.NewDecoder(),
+		sessionManager: sessionManager,
+		pageSize:       20,
+	}
+}
+
+// testServer wraps an httptest.Server whose client keeps cookies (for the
+// session and CSRF cookies) and doesn't follow redirects, so tests can check
+// them.
+type testServer struct {
+	*httptest.Server
+}
+
+func newTestServer(t *testing.T, h http.Handler) *testServer {
+	t.Helper()
+
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Client().Jar = jar
+
+	ts.Client().CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &testServer{ts}
+}
+
+// do sends req and returns the status code, headers and body of the
+// response.
+func (ts *testServer) do(t *testing.T, req *http.Request) (int, http.Header, string) {
+	t.Helper()
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(bytes.TrimSpace(body))
+}
+
+// get makes a GET request to urlPath. Each header is a "Name: value" pair.
+func (ts *testServer) get(t *testing.T, urlPath string, headers ...string) (int, http.Header, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+urlPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setHeaders(req, headers)
+
+	return ts.do(t, req)
+}
+
+// postForm sends form to urlPath, with the Origin header a browser would
+// add (nosurf checks it).
+func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values, headers ...string) (int, http.Header, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+urlPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", ts.URL)
+	setHeaders(req, headers)
+
+	return ts.do(t, req)
+}
+
+func setHeaders(req *http.Request, headers []string) {
+	for _, h := range headers {
+		name, value, _ := strings.Cut(h, ": ")
+		req.Header.Set(name, value)
+	}
+}
+
+var csrfTokenRX = regexp.MustCompile(`<input type='hidden' name='csrf_token' value='(.+?)'>`)
+
+// csrfToken fetches the page at urlPath and returns the CSRF token from its
+// first form.
+func (ts *testServer) csrfToken(t *testing.T, urlPath string) string {
+	t.Helper()
+
+	_, _, body := ts.get(t, urlPath)
+	matches := csrfTokenRX.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatalf("no CSRF token found in %s", urlPath)
+	}
+
+	return html.UnescapeString(matches[1])
+}
+
+// signup creates a user with the password "pa$$word" through the memory
+// store, and returns their id.
+func signup(t *testing.T, app *application, name, email string) int {
+	t.Helper()
+
+	err := app.users.Insert(t.Context(), name, email, "pa$$word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := app.users.IDByEmail(t.Context(), email)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+// login logs in through the login form, so the client's session belongs to
+// the user with email.
+func (ts *testServer) login(t *testing.T, email string) {
+	t.Helper()
+
+	form := url.Values{}
+	form.Add("email", email)
+	form.Add("password", "pa$$word")
+	form.Add("csrf_token", ts.csrfToken(t, "/user/login"))
+
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("logging in as %s: got status %d; want %d", email, code, http.StatusSeeOther)
+	}
+}
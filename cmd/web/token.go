@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"snippetbox.vishalborana2407.net/internal/models"
+)
+
+// runToken runs one of the token subcommands, which let operators manage
+// users' API tokens from the command line:
+//
+//	web token create -email alice@example.com -name deploy [-scopes read,write] [-expires 90]
+//	web token list -email alice@example.com
+//	web token revoke -email alice@example.com -id 3
+//
+// Output meant for the operator (like a new token) is written to out.
+func runToken(out io.Writer, users models.UserStore, tokens models.TokenStore, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing token command (want create, list or revoke)")
+	}
+
+	command := args[0]
+	fs := flag.NewFlagSet("token "+command, flag.ContinueOnError)
+	email := fs.String("email", "", "Email address of the user who owns the token")
+
+	var (
+		name    *string
+		scopes  *string
+		expires *int
+		id      *int
+	)
+	switch command {
+	case "create":
+		name = fs.String("name", "", "Name to show for the token")
+		scopes = fs.String("scopes", "read,write", "Comma-separated scopes (read, write)")
+		expires = fs.Int("expires", 90, "Days until the token expires (0 for never)")
+	case "list":
+	case "revoke":
+		id = fs.Int("id", 0, "Id of the token to revoke (see token list)")
+	default:
+		return fmt.Errorf("unknown token command %q (want create, list or revoke)", command)
+	}
+
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("-email is required")
+	}
+
+	ctx := context.Background()
+
+	userID, err := users.IDByEmail(ctx, normalizeEmail(*email))
+	if errors.Is(err, models.ErrNoRecord) {
+		return fmt.Errorf("no user with email %q", *email)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "create":
+		form := tokenCreateForm{Name: *name, Scopes: strings.Split(*scopes, ","), Expires: *expires}
+		form.validate()
+		for _, field := range []string{"name", "scopes", "expires"} {
+			if msg, ok := form.FieldErrors[field]; ok {
+				return fmt.Errorf("-%s: %s", field, msg)
+			}
+		}
+
+		token, err := tokens.Insert(ctx, userID, form.Name, form.Scopes, form.Expires)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, token)
+
+	case "list":
+		list, err := tokens.ByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tSCOPES\tCREATED\tEXPIRES\tLAST USED")
+		for _, t := range list {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, strings.Join(t.Scopes, ","),
+				humanDate(t.Created), orNever(t.Expires), orNever(t.LastUsed))
+		}
+		return tw.Flush()
+
+	case "revoke":
+		err := tokens.Revoke(ctx, *id, userID)
+		if errors.Is(err, models.ErrNoRecord) {
+			return fmt.Errorf("%s has no token with id %d", *email, *id)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Revoked token %d\n", *id)
+	}
+
+	return nil
+}
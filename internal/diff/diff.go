@@ -0,0 +1,257 @@
+// Package diff compares two versions of a text line by line, and lays the
+// result out for the unified and side-by-side views of a snippet's history.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op says what happened to a line between the old and new text.
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// String returns the name of the operation, which the templates use as a CSS
+// class.
+func (op Op) String() string {
+	switch op {
+	case Delete:
+		return "delete"
+	case Insert:
+		return "insert"
+	default:
+		return "equal"
+	}
+}
+
+// Prefix returns the character which marks the line in a unified diff.
+func (op Op) Prefix() string {
+	switch op {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// Line is one line of a diff. Old and New are its 1-based line numbers in
+// the old and new text; a deleted line has no New number and an inserted
+// line no Old number (both are 0).
+type Line struct {
+	Op   Op
+	Text string
+	Old  int
+	New  int
+}
+
+// maxCells limits the size of the table used to find the longest common
+// subsequence. Texts which differ by more than this are shown as entirely
+// replaced rather than using lots of memory.
+const maxCells = 4 << 20
+
+// Lines compares old and new line by line. Lines which aren't in the longest
+// common subsequence of the two are marked as deleted or inserted, with the
+// deletions coming first wherever a run of lines was changed.
+func Lines(old, new string) []Line {
+	a, b := split(old), split(new)
+
+	// Lines shared at the start and end don't need the expensive part.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, Line{Op: Equal, Text: a[i], Old: i + 1, New: i + 1})
+	}
+
+	lines = append(lines, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+
+	for i := 0; i < suffix; i++ {
+		oi, ni := len(a)-suffix+i, len(b)-suffix+i
+		lines = append(lines, Line{Op: Equal, Text: a[oi], Old: oi + 1, New: ni + 1})
+	}
+
+	return lines
+}
+
+// middle diffs the part of the texts between the common prefix and suffix.
+// oldOffset and newOffset are the number of lines before a and b.
+func middle(a, b []string, oldOffset, newOffset int) []Line {
+	n, m := len(a), len(b)
+	var lines []Line
+
+	if n*m > maxCells {
+		for i, text := range a {
+			lines = append(lines, Line{Op: Delete, Text: text, Old: oldOffset + i + 1})
+		}
+		for j, text := range b {
+			lines = append(lines, Line{Op: Insert, Text: text, New: newOffset + j + 1})
+		}
+		return lines
+	}
+
+	// lcs[i*(m+1)+j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else {
+				lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			lines = append(lines, Line{Op: Equal, Text: a[i], Old: oldOffset + i + 1, New: newOffset + j + 1})
+			i++
+			j++
+		case j == m || (i < n && lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]):
+			lines = append(lines, Line{Op: Delete, Text: a[i], Old: oldOffset + i + 1})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: b[j], New: newOffset + j + 1})
+			j++
+		}
+	}
+
+	return lines
+}
+
+// split breaks text into lines, treating \r\n the same as \n (browsers send
+// textarea contents with \r\n line endings). Empty text has no lines.
+func split(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Hunk is a group of changed lines along with some unchanged lines around
+// them, as in the output of diff -u.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []Line
+}
+
+// Header returns the "@@ -1,4 +1,5 @@" line which starts the hunk.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// Unified groups the changes in lines into hunks, keeping up to context
+// unchanged lines before and after each change. Changes which are closer
+// together than that share a hunk. It returns nil if nothing changed.
+func Unified(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			i++
+			continue
+		}
+
+		// Start a hunk context lines before this change, and keep extending
+		// it while there are at most 2*context unchanged lines before the
+		// next change (so the two hunks would touch or overlap).
+		start := max(i-context, 0)
+		end := i
+		for j := i; j < len(lines) && j <= end+2*context+1; j++ {
+			if lines[j].Op != Equal {
+				end = j
+			}
+		}
+		end = min(end+context+1, len(lines))
+
+		hunks = append(hunks, newHunk(lines[start:end]))
+		i = end
+	}
+
+	return hunks
+}
+
+// newHunk works out the line ranges covered by lines.
+func newHunk(lines []Line) Hunk {
+	h := Hunk{Lines: lines}
+
+	for _, l := range lines {
+		if l.Old != 0 {
+			if h.OldStart == 0 {
+				h.OldStart = l.Old
+			}
+			h.OldLines++
+		}
+		if l.New != 0 {
+			if h.NewStart == 0 {
+				h.NewStart = l.New
+			}
+			h.NewLines++
+		}
+	}
+
+	return h
+}
+
+// Row is one row of a side-by-side diff. Left is the line from the old text
+// and Right the line from the new text; either may be the zero Line (with no
+// line number) when a line was only deleted or only inserted.
+type Row struct {
+	Left, Right Line
+}
+
+// SideBySide lays lines out in two columns. Unchanged lines appear on both
+// sides, and a run of deleted lines followed by inserted ones is shown next
+// to each other as a change.
+func SideBySide(lines []Line) []Row {
+	var rows []Row
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			rows = append(rows, Row{Left: lines[i], Right: lines[i]})
+			i++
+			continue
+		}
+
+		var deleted, inserted []Line
+		for i < len(lines) && lines[i].Op == Delete {
+			deleted = append(deleted, lines[i])
+			i++
+		}
+		for i < len(lines) && lines[i].Op == Insert {
+			inserted = append(inserted, lines[i])
+			i++
+		}
+
+		for k := 0; k < max(len(deleted), len(inserted)); k++ {
+			var row Row
+			if k < len(deleted) {
+				row.Left = deleted[k]
+			}
+			if k < len(inserted) {
+				row.Right = inserted[k]
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
@@ -0,0 +1,213 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// render writes lines the way diff -u does ("-old", "+new", " same"), with
+// their line numbers, so test cases can be read at a glance.
+func render(lines []Line) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = fmt.Sprintf("%s%s %d,%d", l.Op.Prefix(), l.Text, l.Old, l.New)
+	}
+	return out
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{
+			name: "Empty",
+			want: []string{},
+		},
+		{
+			name: "Unchanged",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: []string{" a 1,1", " b 2,2"},
+		},
+		{
+			name: "All inserted",
+			new:  "a\nb",
+			want: []string{"+a 0,1", "+b 0,2"},
+		},
+		{
+			name: "All deleted",
+			old:  "a\nb",
+			want: []string{"-a 1,0", "-b 2,0"},
+		},
+		{
+			name: "Changed line",
+			old:  "a\nb\nc",
+			new:  "a\nB\nc",
+			want: []string{" a 1,1", "-b 2,0", "+B 0,2", " c 3,3"},
+		},
+		{
+			name: "Inserted in the middle",
+			old:  "a\nc",
+			new:  "a\nb\nc",
+			want: []string{" a 1,1", "+b 0,2", " c 2,3"},
+		},
+		{
+			name: "Moved line",
+			old:  "a\nb\nc\nd",
+			new:  "b\nc\na\nd",
+			want: []string{"-a 1,0", " b 2,1", " c 3,2", "+a 0,3", " d 4,4"},
+		},
+		{
+			name: "CRLF line endings",
+			old:  "a\r\nb\r\n",
+			new:  "a\nb\n",
+			want: []string{" a 1,1", " b 2,2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := render(Lines(tt.old, tt.new))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLinesTooBig checks that texts too different to compare within maxCells
+// are shown as replaced outright, while the lines they share at the start and
+// end still match up.
+func TestLinesTooBig(t *testing.T) {
+	// 2049 x 2049 distinct lines is just over 4M cells.
+	n := 2049
+	old := make([]string, n)
+	new := make([]string, n)
+	for i := range n {
+		old[i] = fmt.Sprintf("old %d", i)
+		new[i] = fmt.Sprintf("new %d", i)
+	}
+	if n*n <= maxCells {
+		t.Fatalf("%d lines aren't enough to go over maxCells", n)
+	}
+
+	lines := Lines("first\n"+strings.Join(old, "\n")+"\nlast", "first\n"+strings.Join(new, "\n")+"\nlast")
+
+	if got, want := len(lines), 2*n+2; got != want {
+		t.Fatalf("got %d lines; want %d", got, want)
+	}
+	if lines[0].Op != Equal || lines[len(lines)-1].Op != Equal {
+		t.Errorf("got %v and %v at the ends; want equal lines", lines[0].Op, lines[len(lines)-1].Op)
+	}
+	for i, l := range lines[1 : n+1] {
+		if l.Op != Delete || l.Old != i+2 {
+			t.Fatalf("line %d: got %v of old line %d; want delete of old line %d", i+1, l.Op, l.Old, i+2)
+		}
+	}
+	for i, l := range lines[n+1 : 2*n+1] {
+		if l.Op != Insert || l.New != i+2 {
+			t.Fatalf("line %d: got %v of new line %d; want insert of new line %d", n+i+1, l.Op, l.New, i+2)
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	// Twelve lines with changes at lines 2 and 11, far enough apart (with
+	// one line of context) to need a hunk each.
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12"
+	new := "1\ntwo\n3\n4\n5\n6\n7\n8\n9\n10\neleven\n12"
+
+	tests := []struct {
+		name    string
+		old     string
+		context int
+		want    []string
+	}{
+		{
+			name:    "No changes",
+			old:     new,
+			context: 3,
+			want:    []string{},
+		},
+		{
+			name:    "Separate hunks",
+			old:     old,
+			context: 1,
+			want:    []string{"@@ -1,3 +1,3 @@", "@@ -10,3 +10,3 @@"},
+		},
+		{
+			name:    "Shared hunk",
+			old:     old,
+			context: 4,
+			want:    []string{"@@ -1,12 +1,12 @@"},
+		},
+		{
+			name:    "No context",
+			old:     old,
+			context: 0,
+			want:    []string{"@@ -2,1 +2,1 @@", "@@ -11,1 +11,1 @@"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks := Unified(Lines(tt.old, new), tt.context)
+			got := []string{}
+			for _, h := range hunks {
+				got = append(got, h.Header())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string // "left|right", with the Text of each side
+	}{
+		{
+			name: "Unchanged",
+			old:  "a\nb",
+			new:  "a\nb",
+			want: []string{"a|a", "b|b"},
+		},
+		{
+			name: "Changed line",
+			old:  "a\nb\nc",
+			new:  "a\nB\nc",
+			want: []string{"a|a", "b|B", "c|c"},
+		},
+		{
+			name: "More deleted than inserted",
+			old:  "a\nb\nc",
+			new:  "x",
+			want: []string{"a|x", "b|", "c|"},
+		},
+		{
+			name: "Only inserted",
+			old:  "a",
+			new:  "a\nb\nc",
+			want: []string{"a|a", "|b", "|c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, row := range SideBySide(Lines(tt.old, tt.new)) {
+				got = append(got, row.Left.Text+"|"+row.Right.Text)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
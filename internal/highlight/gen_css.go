@@ -0,0 +1,31 @@
+//go:build ignore
+
+// gen_css.go writes the stylesheet for the classes in highlighted snippets
+// to ui/static/css/chroma.css. Run it with `go generate ./internal/highlight`
+// after changing the style or the formatter options.
+package main
+
+import (
+	"log"
+	"os"
+
+	"snippetbox.vishalborana2407.net/internal/highlight"
+)
+
+func main() {
+	f, err := os.Create("../../ui/static/css/chroma.css")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteString("/* Generated by internal/highlight/gen_css.go. DO NOT EDIT. */\n")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = highlight.WriteCSS(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,200 @@
+// Package highlight renders snippets as syntax-highlighted HTML, using
+// Chroma. The HTML only uses CSS classes (no inline styles), so it works
+// under our Content-Security-Policy; the matching stylesheet is
+// ui/static/css/chroma.css, which is generated by `go generate`.
+package highlight
+
+//go:generate go run gen_css.go
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// Languages are the languages offered in the snippet forms, by their Chroma
+// names. Any other language Chroma knows is accepted too (see Lookup), for
+// API clients which ask for one.
+var Languages = []string{
+	"Bash", "C", "C++", "C#", "CSS", "Diff", "Docker", "Go", "HTML", "INI",
+	"Java", "JavaScript", "JSON", "Kotlin", "Lua", "Makefile", "markdown",
+	"PHP", "PowerShell", "Python", "Ruby", "Rust", "SQL", "Swift",
+	"Terraform", "TOML", "TypeScript", "XML", "YAML",
+}
+
+// MaxNameLength is the longest language name we store, the size of the
+// snippets.language column.
+const MaxNameLength = 32
+
+// Style is the Chroma style the stylesheet is generated from.
+const Style = "github"
+
+// MaxBytes is the largest snippet we highlight. Bigger ones are shown as
+// plain text (still with line numbers), to keep a huge paste from tying up
+// the server.
+const MaxBytes = 256 << 10
+
+// Lookup returns the canonical name of a language, given its name or one of
+// its aliases in any case ("golang" and "GO" both give "Go"). ok is false if
+// Chroma doesn't know the language.
+func Lookup(name string) (canonical string, ok bool) {
+	if name == "" {
+		return "", false
+	}
+	lexer := lexers.Get(name)
+	if lexer == nil {
+		return "", false
+	}
+	canonical = lexer.Config().Name
+	return canonical, len(canonical) <= MaxNameLength
+}
+
+// hints are patterns which give away a few common languages that Chroma's
+// own content analysis doesn't recognise (or gets wrong).
+var hints = []struct {
+	rx       *regexp.Regexp
+	language string
+}{
+	{regexp.MustCompile(`(?m)^package \w+\s*$`), "Go"},
+	{regexp.MustCompile(`(?m)^\s*(def|class) \w+.*:\s*$`), "Python"},
+	{regexp.MustCompile(`(?im)^\s*(SELECT|INSERT INTO|DELETE FROM|CREATE (TABLE|INDEX)|ALTER TABLE|UPDATE \w+ SET)\b`), "SQL"},
+	{regexp.MustCompile(`(?m)^(apiVersion|kind|metadata):`), "YAML"},
+	{regexp.MustCompile(`(?m)^FROM \S+`), "Docker"},
+	{regexp.MustCompile(`^\s*[{\[]\s*"`), "JSON"},
+}
+
+// Detect guesses the language of a snippet, for when the user didn't pick
+// one. A title which looks like a file name ("main.go") decides it;
+// otherwise the content is checked against a few hints and then analysed by
+// Chroma, keeping only an answer from Languages (its analysis is too keen to
+// pick obscure languages). It returns "" if it can't tell.
+func Detect(title, content string) string {
+	if lexer := lexers.Match(strings.TrimSpace(title)); lexer != nil {
+		if name, ok := Lookup(lexer.Config().Name); ok && name != "plaintext" {
+			return name
+		}
+	}
+
+	for _, h := range hints {
+		if h.rx.MatchString(content) {
+			return h.language
+		}
+	}
+
+	if lexer := lexers.Analyse(content); lexer != nil {
+		name := lexer.Config().Name
+		for _, l := range Languages {
+			if l == name {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
+
+// lineOptions are the formatter options for whole snippets: a table with the
+// line numbers in one column and the code in the other, so copying the code
+// doesn't copy the numbers too. Each number is a link to its own anchor
+// (#L42), for linking to a line.
+var lineOptions = []html.Option{
+	html.WithClasses(true),
+	html.WithLineNumbers(true),
+	html.LineNumbersInTable(true),
+	html.WithLinkableLineNumbers(true, "L"),
+	html.TabWidth(4),
+}
+
+var formatter = html.New(lineOptions...)
+
+// blockFormatter is for code inside other content (fenced code blocks in
+// Markdown snippets), where line numbers would just get in the way. It uses
+// the same classes as formatter, so the one stylesheet covers both.
+var blockFormatter = html.New(
+	html.WithClasses(true),
+	html.TabWidth(4),
+)
+
+// HTML returns content highlighted as language, which can be "" for plain
+// text. It's safe to put straight into a page: Chroma escapes the content.
+func HTML(content, language string) (template.HTML, error) {
+	tokens, err := tokenise(content, language)
+	if err != nil {
+		return "", err
+	}
+	return format(formatter, chroma.Literator(tokens...))
+}
+
+// HTMLLines is like HTML but only returns the lines in r, numbered as they
+// are in the whole snippet and marked as highlighted. r must be within the
+// content (see LineCount). The whole snippet is still tokenised, so lines in
+// the middle of, say, a block comment are coloured correctly.
+func HTMLLines(content, language string, r Range) (template.HTML, error) {
+	tokens, err := tokenise(content, language)
+	if err != nil {
+		return "", err
+	}
+
+	lines := chroma.SplitTokensIntoLines(tokens)
+	if r.From < 1 || r.To < r.From || r.To > len(lines) {
+		return "", fmt.Errorf("highlight: lines %s out of range 1-%d", r, len(lines))
+	}
+
+	var selected []chroma.Token
+	for _, line := range lines[r.From-1 : r.To] {
+		selected = append(selected, line...)
+	}
+
+	f := html.New(append(slices.Clone(lineOptions),
+		html.BaseLineNumber(r.From),
+		html.HighlightLines([][2]int{{r.From, r.To}}),
+	)...)
+	return format(f, chroma.Literator(selected...))
+}
+
+// Block is like HTML but without line numbers. language can be any name or
+// alias Chroma knows; an unknown one gives plain text.
+func Block(content, language string) (template.HTML, error) {
+	tokens, err := tokenise(content, language)
+	if err != nil {
+		return "", err
+	}
+	return format(blockFormatter, chroma.Literator(tokens...))
+}
+
+// tokenise splits content into tokens with the lexer for language, or as
+// plain text if the language is unknown or the content is too big.
+func tokenise(content, language string) ([]chroma.Token, error) {
+	var lexer chroma.Lexer
+	if language != "" && len(content) <= MaxBytes {
+		lexer = lexers.Get(language)
+	}
+	if lexer == nil {
+		lexer = lexers.Get("plaintext")
+	}
+
+	return chroma.Tokenise(chroma.Coalesce(lexer), nil, content)
+}
+
+func format(f *html.Formatter, iterator chroma.Iterator) (template.HTML, error) {
+	var b strings.Builder
+	err := f.Format(&b, styles.Get(Style), iterator)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(b.String()), nil
+}
+
+// WriteCSS writes the stylesheet for the HTML returned by HTML().
+func WriteCSS(w io.Writer) error {
+	return formatter.WriteCSS(w, styles.Get(Style))
+}
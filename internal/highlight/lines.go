@@ -0,0 +1,59 @@
+package highlight
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Range is a range of lines in a snippet, numbered from 1, with both From
+// and To included. It's written "10-20", or just "42" for a single line, in
+// ?lines= queries; the #L10-L20 fragments are handled by ui/static/js/main.js.
+type Range struct {
+	From, To int
+}
+
+// ErrInvalidRange is returned by ParseRange for anything but "N" or "N-M"
+// with 1 <= N <= M.
+var ErrInvalidRange = errors.New("highlight: invalid line range")
+
+// ParseRange parses a range written by Range.String.
+func ParseRange(s string) (Range, error) {
+	from, to, found := strings.Cut(s, "-")
+	if !found {
+		to = from
+	}
+
+	var (
+		r   Range
+		err error
+	)
+	r.From, err = strconv.Atoi(from)
+	if err != nil || r.From < 1 {
+		return Range{}, ErrInvalidRange
+	}
+	r.To, err = strconv.Atoi(to)
+	if err != nil || r.To < r.From {
+		return Range{}, ErrInvalidRange
+	}
+
+	return r, nil
+}
+
+// String formats r for a ?lines= query.
+func (r Range) String() string {
+	if r.From == r.To {
+		return strconv.Itoa(r.From)
+	}
+	return strconv.Itoa(r.From) + "-" + strconv.Itoa(r.To)
+}
+
+// LineCount returns the number of lines in content, the way they're
+// numbered by HTML: a final newline doesn't start another line.
+func LineCount(content string) int {
+	n := strings.Count(content, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		n++
+	}
+	return n
+}
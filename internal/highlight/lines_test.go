@@ -0,0 +1,64 @@
+package highlight
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Range
+		wantErr error
+	}{
+		{name: "Range", s: "10-20", want: Range{From: 10, To: 20}},
+		{name: "Single line", s: "42", want: Range{From: 42, To: 42}},
+		{name: "One line range", s: "7-7", want: Range{From: 7, To: 7}},
+		{name: "Empty", s: "", wantErr: ErrInvalidRange},
+		{name: "Zero", s: "0-2", wantErr: ErrInvalidRange},
+		{name: "Backwards", s: "5-3", wantErr: ErrInvalidRange},
+		{name: "Open ended", s: "5-", wantErr: ErrInvalidRange},
+		{name: "Fragment style", s: "L10-L20", wantErr: ErrInvalidRange},
+		{name: "Not a number", s: "x", wantErr: ErrInvalidRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRange(tt.s)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v; want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+			if err == nil {
+				if round, _ := ParseRange(got.String()); round != got {
+					t.Errorf("%q doesn't parse back to %+v", got.String(), got)
+				}
+			}
+		})
+	}
+}
+
+func TestLineCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{name: "Empty", content: "", want: 0},
+		{name: "One line", content: "a", want: 1},
+		{name: "Final newline", content: "a\nb\n", want: 2},
+		{name: "No final newline", content: "a\nb", want: 2},
+		{name: "Blank lines", content: "a\n\n\n", want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LineCount(tt.content); got != tt.want {
+				t.Errorf("got %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
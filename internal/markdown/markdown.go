@@ -0,0 +1,100 @@
+// Package markdown renders Markdown snippets to HTML, using goldmark with the
+// GitHub extensions (tables, task lists, strikethrough and autolinks).
+//
+// Snippets are written by anyone with an account, so the HTML goes through
+// two layers of protection before it reaches a page: goldmark drops any raw
+// HTML in the source, and what it produces is then cleaned by a bluemonday
+// policy which only keeps formatting (no scripts, styles, event handlers or
+// javascript: links).
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+
+	"snippetbox.vishalborana2407.net/internal/highlight"
+)
+
+// md leaves out goldmark's html.WithUnsafe() option, so raw HTML in the
+// source is replaced by an "omitted" comment rather than passed through.
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(
+		// A lower number wins, so this replaces goldmark's own renderer
+		// for fenced code blocks (priority 1000).
+		renderer.WithNodeRenderers(util.Prioritized(codeBlockRenderer{}, 100)),
+	),
+)
+
+// chromaClass matches the class names in highlighted code: "chroma" on the
+// <pre> and short token classes like "kd" or "s2" on the <span>s.
+var chromaClass = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// policy starts from bluemonday's policy for user-generated content, which
+// allows the usual formatting elements (including tables) and adds
+// rel="nofollow" to links, and allows the few extra attributes our HTML needs.
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(chromaClass).OnElements("pre", "code", "span")
+	// task list items: <input checked="" disabled="" type="checkbox">
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}()
+
+// Render returns src rendered as HTML, sanitized so it's safe to put
+// straight into a page.
+func Render(src string) (template.HTML, error) {
+	var buf bytes.Buffer
+	err := md.Convert([]byte(src), &buf)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(policy.SanitizeBytes(buf.Bytes())), nil
+}
+
+// codeBlockRenderer highlights fenced code blocks with the language given
+// after the opening fence (```go), the same way whole snippets are.
+type codeBlockRenderer struct{}
+
+func (r codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.render)
+}
+
+func (r codeBlockRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	block := node.(*ast.FencedCodeBlock)
+
+	var code strings.Builder
+	lines := block.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+
+	// Language() is nil for a fence without one, which gives plain text.
+	html, err := highlight.Block(code.String(), string(block.Language(source)))
+	if err != nil {
+		return ast.WalkStop, err
+	}
+
+	_, err = w.WriteString(string(html))
+	if err != nil {
+		return ast.WalkStop, err
+	}
+
+	return ast.WalkSkipChildren, nil
+}
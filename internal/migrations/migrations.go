@@ -0,0 +1,309 @@
+// Package migrations applies the versioned SQL migrations embedded in the
+// binary, so a fresh database can be provisioned by snippetbox itself.
+//
+// Each dialect has its own directory of files named like
+// 0001_create_snippets.up.sql and 0001_create_snippets.down.sql. Applied
+// versions are recorded in a schema_migrations table.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// ErrNoMigrations is returned by Down() when there is nothing to roll back.
+var ErrNoMigrations = errors.New("migrations: no applied migrations")
+
+// Migration holds a single versioned migration.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes a migration and whether it has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies the migrations for one dialect ("mysql", "postgres" or
+// "sqlite") to a database.
+type Migrator struct {
+	DB      *sql.DB
+	Dialect string
+}
+
+// Migrations returns every migration for the dialect, ordered by version.
+func (m *Migrator) Migrations() ([]Migration, error) {
+	names, err := fs.Glob(files, m.Dialect+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("migrations: unsupported dialect %q", m.Dialect)
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, name := range names {
+		// 0001_create_snippets.up.sql -> "0001_create_snippets", "up"
+		base := strings.TrimSuffix(path.Base(name), ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+
+		prefix, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migrations: bad file name %q", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migrations: bad version in %q", name)
+		}
+
+		body, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: label}
+			byVersion[version] = mig
+		}
+
+		switch direction {
+		case ".up":
+			mig.Up = string(body)
+		case ".down":
+			mig.Down = string(body)
+		default:
+			return nil, fmt.Errorf("migrations: %q is neither .up.sql nor .down.sql", name)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in order and returns the ones it
+// applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+
+	for _, st := range statuses {
+		if st.Applied {
+			continue
+		}
+
+		err := m.run(st.Migration, st.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(m.rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+				st.Version, st.Name, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return applied, err
+		}
+
+		applied = append(applied, st.Migration)
+	}
+
+	return applied, nil
+}
+
+// Down rolls back the most recently applied migration and returns it.
+func (m *Migrator) Down() (Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return Migration{}, err
+	}
+
+	for i := len(statuses) - 1; i >= 0; i-- {
+		st := statuses[i]
+		if !st.Applied {
+			continue
+		}
+
+		err := m.run(st.Migration, st.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(m.rebind("DELETE FROM schema_migrations WHERE version = ?"), st.Version)
+			return err
+		})
+		if err != nil {
+			return Migration{}, err
+		}
+
+		return st.Migration, nil
+	}
+
+	return Migration{}, ErrNoMigrations
+}
+
+// Status returns every migration along with whether it has been applied.
+func (m *Migrator) Status() ([]Status, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.ensureTable()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := map[int]time.Time{}
+
+	for rows.Next() {
+		var (
+			version int
+			at      time.Time
+		)
+		err := rows.Scan(&version, &at)
+		if err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(migrations))
+	for i, mig := range migrations {
+		at, ok := appliedAt[mig.Version]
+		statuses[i] = Status{Migration: mig, Applied: ok, AppliedAt: at}
+	}
+
+	return statuses, nil
+}
+
+// ensureTable creates the schema_migrations table if it doesn't exist yet.
+func (m *Migrator) ensureTable() error {
+	statement := `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`
+	if m.Dialect == "mysql" || m.Dialect == "sqlite" {
+		// DATETIME keeps MySQL from applying its TIMESTAMP auto-update rules,
+		// and lets the SQLite driver scan the column into a time.Time.
+		statement = strings.Replace(statement, "TIMESTAMP", "DATETIME", 1)
+	}
+
+	_, err := m.DB.Exec(statement)
+	return err
+}
+
+// run executes the statements in body followed by record() in a single
+// transaction. Note that MySQL implicitly commits DDL statements, so there a
+// failed migration may be left half applied and need fixing by hand.
+func (m *Migrator) run(mig Migration, body string, record func(*sql.Tx) error) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range splitStatements(body) {
+		_, err := tx.Exec(statement)
+		if err != nil {
+			return fmt.Errorf("migrations: %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	err = record(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// rebind swaps ? placeholders for $1, $2... when talking to Postgres.
+func (m *Migrator) rebind(query string) string {
+	if m.Dialect != "postgres" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// splitStatements splits a migration file into individual statements, since
+// not every driver accepts several statements in one Exec(). Statements end
+// with a semicolon at the end of a line. A BEGIN ... END; block (as used by
+// triggers) is kept together, and comment-only chunks are dropped.
+func splitStatements(body string) []string {
+	var (
+		statements []string
+		current    []string
+		inBlock    bool
+	)
+
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.ToUpper(strings.TrimSpace(line))
+		if strings.HasPrefix(trimmed, "--") && len(current) == 0 {
+			continue
+		}
+
+		current = append(current, line)
+
+		switch {
+		case strings.HasSuffix(trimmed, "BEGIN"):
+			inBlock = true
+		case inBlock && trimmed == "END;":
+			inBlock = false
+			fallthrough
+		case !inBlock && strings.HasSuffix(trimmed, ";"):
+			statements = append(statements, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+
+	if rest := strings.TrimSpace(strings.Join(current, "\n")); rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
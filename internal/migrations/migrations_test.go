@@ -0,0 +1,147 @@
+package migrations
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "Empty",
+			body: "",
+			want: nil,
+		},
+		{
+			name: "Comments only",
+			body: "-- nothing to undo\n",
+			want: nil,
+		},
+		{
+			name: "One statement",
+			body: "-- a comment\nCREATE TABLE t (\n    id INTEGER\n);\n",
+			want: []string{"CREATE TABLE t (\n    id INTEGER\n);"},
+		},
+		{
+			name: "Several statements",
+			body: "CREATE TABLE t (id INTEGER);\n-- index it\nCREATE INDEX i ON t(id);\n",
+			want: []string{"CREATE TABLE t (id INTEGER);", "CREATE INDEX i ON t(id);"},
+		},
+		{
+			name: "Trigger",
+			body: "CREATE TRIGGER t_insert AFTER INSERT ON t BEGIN\n    INSERT INTO u VALUES (new.id);\n    DELETE FROM v;\nEND;\nDROP TABLE w;\n",
+			want: []string{
+				"CREATE TRIGGER t_insert AFTER INSERT ON t BEGIN\n    INSERT INTO u VALUES (new.id);\n    DELETE FROM v;\nEND;",
+				"DROP TABLE w;",
+			},
+		},
+		{
+			name: "Lower case trigger",
+			body: "create trigger t_delete after delete on t begin\n    delete from u;\nend;\n",
+			want: []string{"create trigger t_delete after delete on t begin\n    delete from u;\nend;"},
+		},
+		{
+			name: "No final semicolon",
+			body: "UPDATE t SET id = 1;\nUPDATE t SET id = 2\n",
+			want: []string{"UPDATE t SET id = 1;", "UPDATE t SET id = 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitStatements(tt.body)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLowercaseUserEmails checks that migration 14 refuses to run, rather
+// than lock an account out, when emails differ only in case.
+func TestLowercaseUserEmails(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// every connection to :memory: gets a database of its own
+	db.SetMaxOpenConns(1)
+
+	m := &Migrator{DB: db, Dialect: "sqlite"}
+
+	// Get to just before migration 14 (its down migration does nothing).
+	_, err = m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mig, err := m.Down()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mig.Version != 14 {
+		t.Fatalf("rolled back migration %d; want 14", mig.Version)
+	}
+
+	_, err = db.Exec(`INSERT INTO users (name, email, hashed_password, created) VALUES
+    ('Alice', 'Alice@example.com', 'x', datetime()),
+    ('Alice again', 'ALICE@example.com', 'x', datetime()),
+    ('Bob', 'Bob@Example.com', 'x', datetime())`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emails := func() []string {
+		t.Helper()
+		rows, err := db.Query("SELECT email FROM users ORDER BY id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		var emails []string
+		for rows.Next() {
+			var email string
+			err := rows.Scan(&email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			emails = append(emails, email)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return emails
+	}
+
+	_, err = m.Up()
+	if err == nil || !strings.Contains(err.Error(), "users_emails_differ_only_in_case") {
+		t.Fatalf("got error %v; want users_emails_differ_only_in_case", err)
+	}
+	want := []string{"Alice@example.com", "ALICE@example.com", "Bob@Example.com"}
+	if got := emails(); !slices.Equal(got, want) {
+		t.Errorf("after the failed migration got emails %q; want %q", got, want)
+	}
+
+	_, err = db.Exec("DELETE FROM users WHERE name = 'Alice again'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"alice@example.com", "bob@example.com"}
+	if got := emails(); !slices.Equal(got, want) {
+		t.Errorf("got emails %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,11 @@
+-- IF NOT EXISTS lets databases with a hand-created snippets table adopt this
+-- migration. The index lives inside CREATE TABLE because MySQL has no
+-- CREATE INDEX IF NOT EXISTS.
+CREATE TABLE IF NOT EXISTS snippets (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    INDEX idx_snippets_created (created)
+);
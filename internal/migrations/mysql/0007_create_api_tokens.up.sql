@@ -0,0 +1,16 @@
+-- Personal API tokens. Only the SHA-256 hash of each token is kept (as hex),
+-- so a leaked database doesn't leak working tokens. scopes is a
+-- comma-separated list like 'read,write'. expires and last_used are NULL for
+-- tokens which never expire and tokens which haven't been used yet.
+CREATE TABLE api_tokens (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    scopes VARCHAR(100) NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NULL,
+    last_used DATETIME NULL,
+    CONSTRAINT api_tokens_uc_token_hash UNIQUE (token_hash),
+    CONSTRAINT fk_api_tokens_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
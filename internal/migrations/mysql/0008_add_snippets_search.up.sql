@@ -0,0 +1,3 @@
+-- A FULLTEXT index over title and content for Search(). InnoDB only indexes
+-- words of at least innodb_ft_min_token_size (3 by default) characters.
+CREATE FULLTEXT INDEX idx_snippets_search ON snippets(title, content);
@@ -0,0 +1,5 @@
+-- Keyset pagination orders snippets by (created, id). InnoDB already appends
+-- the primary key to every secondary index, but spelling it out says what the
+-- index is for.
+DROP INDEX idx_snippets_created ON snippets;
+CREATE INDEX idx_snippets_created_id ON snippets(created, id);
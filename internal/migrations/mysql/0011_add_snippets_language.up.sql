@@ -0,0 +1,4 @@
+-- The language a snippet is highlighted as, by its Chroma name ('Go',
+-- 'SQL'...). Empty means we couldn't tell, and the snippet is shown as plain
+-- text.
+ALTER TABLE snippets ADD COLUMN language VARCHAR(32) NOT NULL DEFAULT '';
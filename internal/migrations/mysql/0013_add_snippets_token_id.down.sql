@@ -0,0 +1,2 @@
+ALTER TABLE snippets DROP FOREIGN KEY fk_snippets_token;
+ALTER TABLE snippets DROP COLUMN token_id;
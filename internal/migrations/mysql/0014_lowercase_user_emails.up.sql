@@ -0,0 +1,25 @@
+-- Emails are now lower-cased before they're stored or looked up, so store the
+-- existing ones the same way as everywhere else.
+--
+-- With the default case-insensitive collation, the unique key on email
+-- already stops two accounts differing only in case, but a database using a
+-- case-sensitive (_bin or _cs) collation could have them. They can't all be
+-- lower-cased, and there's no telling which one should keep the address, so
+-- the migration stops before changing anything, with a "Check constraint
+-- 'users_emails_differ_only_in_case' is violated" error. List them with
+--
+--   SELECT id, name, email FROM users WHERE LOWER(email) IN
+--     (SELECT LOWER(email) FROM users GROUP BY LOWER(email) HAVING COUNT(*) > 1);
+--
+-- then change the email of (or delete) all but one of each, and migrate again.
+-- (The check needs MySQL 8.0.16 or later; older versions ignore CHECK.)
+DROP TEMPORARY TABLE IF EXISTS email_case_check;
+CREATE TEMPORARY TABLE email_case_check (
+    duplicates INT NOT NULL,
+    CONSTRAINT users_emails_differ_only_in_case CHECK (duplicates = 0)
+);
+INSERT INTO email_case_check (duplicates)
+SELECT COUNT(*) FROM (SELECT LOWER(email) FROM users GROUP BY LOWER(email) HAVING COUNT(*) > 1) AS d;
+DROP TEMPORARY TABLE email_case_check;
+
+UPDATE users SET email = LOWER(email);
@@ -0,0 +1,2 @@
+DROP INDEX idx_snippets_user_created;
+ALTER TABLE snippets DROP COLUMN user_id;
@@ -0,0 +1,7 @@
+-- Snippets posted before user accounts existed have no owner, so user_id is
+-- nullable, and deleting a user keeps their snippets around without one.
+ALTER TABLE snippets
+    ADD COLUMN user_id INTEGER NULL
+    CONSTRAINT fk_snippets_user REFERENCES users(id) ON DELETE SET NULL;
+-- Serves the "my snippets" page, which lists a user's snippets newest first.
+CREATE INDEX idx_snippets_user_created ON snippets(user_id, created);
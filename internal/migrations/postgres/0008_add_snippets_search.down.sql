@@ -0,0 +1,2 @@
+DROP INDEX idx_snippets_search;
+ALTER TABLE snippets DROP COLUMN search;
@@ -0,0 +1,9 @@
+-- A tsvector of each snippet for Search(), kept up to date by Postgres itself.
+-- The 'simple' configuration doesn't stem words or drop stop words, which
+-- suits code better than 'english' does. Title words are weighted higher so
+-- they rank first.
+ALTER TABLE snippets ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', content), 'B')
+) STORED;
+
+CREATE INDEX idx_snippets_search ON snippets USING GIN (search);
@@ -0,0 +1,16 @@
+-- Tags like 'sql' or 'k8s', each stored once and linked to any number of
+-- snippets through snippet_tags. The primary key of snippet_tags finds the
+-- tags of a snippet; idx_snippet_tags_tag finds the snippets with a tag.
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(32) NOT NULL,
+    CONSTRAINT tags_uc_name UNIQUE (name)
+);
+
+CREATE TABLE snippet_tags (
+    snippet_id INTEGER NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (snippet_id, tag_id)
+);
+
+CREATE INDEX idx_snippet_tags_tag ON snippet_tags(tag_id, snippet_id);
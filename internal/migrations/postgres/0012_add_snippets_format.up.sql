@@ -0,0 +1,3 @@
+-- How a snippet's content is shown: 'plain' (highlighted as its language)
+-- or 'markdown' (rendered to HTML).
+ALTER TABLE snippets ADD COLUMN format VARCHAR(16) NOT NULL DEFAULT 'plain';
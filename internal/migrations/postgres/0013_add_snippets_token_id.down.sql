@@ -0,0 +1,2 @@
+DROP INDEX idx_snippets_token;
+ALTER TABLE snippets DROP COLUMN token_id;
@@ -0,0 +1,8 @@
+-- The API token a snippet was created with, if any, so a misbehaving script
+-- can be tracked down to its token. Revoking the token keeps the snippet.
+ALTER TABLE snippets
+    ADD COLUMN token_id INTEGER NULL
+    CONSTRAINT fk_snippets_token REFERENCES api_tokens(id) ON DELETE SET NULL;
+-- Postgres doesn't index foreign keys by itself, and revoking a token has
+-- to find its snippets.
+CREATE INDEX idx_snippets_token ON snippets(token_id);
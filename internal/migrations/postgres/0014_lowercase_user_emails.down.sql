@@ -0,0 +1 @@
+-- The original letter case of emails isn't kept, so there's nothing to undo.
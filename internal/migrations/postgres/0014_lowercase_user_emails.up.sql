@@ -0,0 +1,22 @@
+-- Emails are now lower-cased before they're stored or looked up, so existing
+-- accounts with capitals in their email need lower-casing too to be able to
+-- log in.
+--
+-- Accounts whose emails differ only in case can't all be lower-cased, and
+-- there's no telling which one should keep the address. So if there are any,
+-- the migration stops before changing anything, with a "violates check
+-- constraint users_emails_differ_only_in_case" error. List them with
+--
+--   SELECT id, name, email FROM users WHERE lower(email) IN
+--     (SELECT lower(email) FROM users GROUP BY lower(email) HAVING count(*) > 1);
+--
+-- then change the email of (or delete) all but one of each, and migrate again.
+CREATE TEMPORARY TABLE email_case_check (
+    duplicates INTEGER NOT NULL,
+    CONSTRAINT users_emails_differ_only_in_case CHECK (duplicates = 0)
+);
+INSERT INTO email_case_check (duplicates)
+SELECT count(*) FROM (SELECT lower(email) FROM users GROUP BY lower(email) HAVING count(*) > 1) AS d;
+DROP TABLE email_case_check;
+
+UPDATE users SET email = lower(email) WHERE email <> lower(email);
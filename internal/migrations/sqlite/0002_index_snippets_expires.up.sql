@@ -0,0 +1,2 @@
+-- Lets the janitor find expired snippets without scanning the whole table.
+CREATE INDEX IF NOT EXISTS idx_snippets_expires ON snippets(expires);
@@ -0,0 +1,8 @@
+-- Session data for the scs SQLite store. expiry is a Julian day number.
+CREATE TABLE sessions (
+    token TEXT PRIMARY KEY,
+    data BLOB NOT NULL,
+    expiry REAL NOT NULL
+);
+
+CREATE INDEX sessions_expiry_idx ON sessions (expiry);
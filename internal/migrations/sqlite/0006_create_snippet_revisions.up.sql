@@ -0,0 +1,15 @@
+-- Every version of a snippet's title and content, numbered from 1 for each
+-- snippet. user_id is whoever made that version.
+CREATE TABLE snippet_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    snippet_id INTEGER NOT NULL REFERENCES snippets(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    user_id INTEGER NULL REFERENCES users(id) ON DELETE SET NULL,
+    created DATETIME NOT NULL,
+    CONSTRAINT snippet_revisions_uc_revision UNIQUE (snippet_id, revision)
+);
+-- Existing snippets start out with their current text as revision 1.
+INSERT INTO snippet_revisions (snippet_id, revision, title, content, user_id, created)
+    SELECT id, 1, title, content, user_id, created FROM snippets;
@@ -0,0 +1,17 @@
+-- Personal API tokens. Only the SHA-256 hash of each token is kept (as hex),
+-- so a leaked database doesn't leak working tokens. scopes is a
+-- comma-separated list like 'read,write'. expires and last_used are NULL for
+-- tokens which never expire and tokens which haven't been used yet.
+CREATE TABLE api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    scopes VARCHAR(100) NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NULL,
+    last_used DATETIME NULL,
+    CONSTRAINT api_tokens_uc_token_hash UNIQUE (token_hash)
+);
+
+CREATE INDEX idx_api_tokens_user ON api_tokens(user_id);
@@ -0,0 +1,25 @@
+-- An FTS5 index over title and content for Search(). It's an external
+-- content table, so the text isn't stored twice: FTS5 reads it back from
+-- snippets, and the triggers keep the index in step with the table.
+CREATE VIRTUAL TABLE snippets_fts USING fts5(
+    title, content,
+    content='snippets', content_rowid='id'
+);
+
+CREATE TRIGGER snippets_fts_insert AFTER INSERT ON snippets BEGIN
+    INSERT INTO snippets_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+-- Removing a row from an external content index needs the old values, which
+-- is what the special 'delete' command is for.
+CREATE TRIGGER snippets_fts_delete AFTER DELETE ON snippets BEGIN
+    INSERT INTO snippets_fts(snippets_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+END;
+
+CREATE TRIGGER snippets_fts_update AFTER UPDATE OF title, content ON snippets BEGIN
+    INSERT INTO snippets_fts(snippets_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+    INSERT INTO snippets_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+-- Index the snippets which already exist.
+INSERT INTO snippets_fts(snippets_fts) VALUES ('rebuild');
@@ -0,0 +1,2 @@
+DROP INDEX idx_snippets_created_id;
+CREATE INDEX idx_snippets_created ON snippets(created);
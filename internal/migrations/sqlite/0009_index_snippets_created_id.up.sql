@@ -0,0 +1,4 @@
+-- Keyset pagination orders snippets by (created, id), and this index lets it
+-- start at any cursor without scanning the rows before it.
+DROP INDEX idx_snippets_created;
+CREATE INDEX idx_snippets_created_id ON snippets(created, id);
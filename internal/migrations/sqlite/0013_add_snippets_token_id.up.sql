@@ -0,0 +1,5 @@
+-- The API token a snippet was created with, if any, so a misbehaving script
+-- can be tracked down to its token. Revoking the token keeps the snippet.
+ALTER TABLE snippets ADD COLUMN token_id INTEGER NULL REFERENCES api_tokens(id) ON DELETE SET NULL;
+-- Revoking a token has to find its snippets.
+CREATE INDEX idx_snippets_token ON snippets(token_id);
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor marks a position in the list of all snippets, newest first, for
+// keyset pagination: ListAfter() and ListBefore() pick up from the snippet
+// with this creation time and id. Unlike an OFFSET, the database can jump
+// straight to a cursor using the (created, id) index, so a page deep into
+// the list is as quick to fetch as the first one, and new snippets don't
+// shift the pages being browsed.
+//
+// The zero Cursor is the start of the list.
+type Cursor struct {
+	Created time.Time
+	ID      int
+}
+
+// Cursor returns the position of s in the list of snippets.
+func (s Snippet) Cursor() Cursor {
+	return Cursor{Created: s.Created, ID: s.ID}
+}
+
+// IsZero reports whether c is the start of the list.
+func (c Cursor) IsZero() bool {
+	return c.ID == 0
+}
+
+// String formats c for use in a URL, as the creation time in microseconds
+// since the Unix epoch and the id: "1760750953000000-42". Microseconds are
+// enough for every backend (Postgres keeps the most precision).
+func (c Cursor) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", c.Created.UnixMicro(), c.ID)
+}
+
+// ParseCursor parses a cursor formatted by Cursor.String.
+func ParseCursor(s string) (Cursor, error) {
+	micros, id, ok := strings.Cut(s, "-")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	us, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil || us < 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	n, err := strconv.Atoi(id)
+	if err != nil || n < 1 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{Created: time.UnixMicro(us).UTC(), ID: n}, nil
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorString(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor Cursor
+		want   string
+	}{
+		{
+			name:   "Zero",
+			cursor: Cursor{},
+			want:   "",
+		},
+		{
+			name:   "Seconds",
+			cursor: Cursor{Created: time.Date(2025, 10, 18, 1, 29, 13, 0, time.UTC), ID: 42},
+			want:   "1760750953000000-42",
+		},
+		{
+			name:   "Microseconds",
+			cursor: Cursor{Created: time.Date(2025, 10, 18, 1, 29, 13, 123456789, time.UTC), ID: 7},
+			want:   "1760750953123456-7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cursor.String(); got != tt.want {
+				t.Errorf("got %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Cursor
+		wantErr error
+	}{
+		{
+			name: "Valid",
+			s:    "1760750953123456-7",
+			want: Cursor{Created: time.Date(2025, 10, 18, 1, 29, 13, 123456000, time.UTC), ID: 7},
+		},
+		{
+			name: "Epoch",
+			s:    "0-1",
+			want: Cursor{Created: time.Unix(0, 0).UTC(), ID: 1},
+		},
+		{name: "Empty", s: "", wantErr: ErrInvalidCursor},
+		{name: "No id", s: "1760750953123456", wantErr: ErrInvalidCursor},
+		{name: "Zero id", s: "1760750953123456-0", wantErr: ErrInvalidCursor},
+		{name: "Negative time", s: "-1-7", wantErr: ErrInvalidCursor},
+		{name: "Not a number", s: "yesterday-7", wantErr: ErrInvalidCursor},
+		{name: "Overflow", s: "99999999999999999999-7", wantErr: ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCursor(tt.s)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v; want %v", err, tt.wantErr)
+			}
+			if !got.Created.Equal(tt.want.Created) || got.ID != tt.want.ID {
+				t.Errorf("got %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCursorRoundTrip checks that a snippet's cursor survives being put in
+// a URL and read back.
+func TestCursorRoundTrip(t *testing.T) {
+	s := Snippet{ID: 99, Created: time.Date(2026, 1, 2, 3, 4, 5, 678901000, time.UTC)}
+
+	got, err := ParseCursor(s.Cursor().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != s.Cursor() {
+		t.Errorf("got %+v; want %+v", got, s.Cursor())
+	}
+}
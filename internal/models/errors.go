@@ -5,3 +5,29 @@ import (
 )
 
 var ErrNoRecord = errors.New("models: no matching record found")
+
+// ErrTimeout is returned when a query takes longer than the model's Timeout.
+var ErrTimeout = errors.New("models: query timed out")
+
+// ErrInvalidCredentials is returned when a user tries to log in with an
+// incorrect email address or password.
+var ErrInvalidCredentials = errors.New("models: invalid credentials")
+
+// ErrDuplicateEmail is returned when a user tries to sign up with an email
+// address that's already in use.
+var ErrDuplicateEmail = errors.New("models: duplicate email")
+
+// ErrNotOwner is returned when a user tries to change a snippet which belongs
+// to somebody else.
+var ErrNotOwner = errors.New("models: snippet belongs to another user")
+
+// ErrInvalidToken is returned when an API token doesn't exist, has been
+// revoked or has expired.
+var ErrInvalidToken = errors.New("models: invalid API token")
+
+// ErrInvalidCursor is returned by ParseCursor for a malformed cursor.
+var ErrInvalidCursor = errors.New("models: invalid cursor")
+
+// ErrInvalidPage is returned when a paged query is given a negative limit or
+// offset.
+var ErrInvalidPage = errors.New("models: invalid limit or offset")
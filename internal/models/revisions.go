@@ -0,0 +1,87 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Revision is one version of a snippet's title and content. Revisions are
+// never changed once written: Insert() records revision 1 and every Update()
+// which changes the title or content adds the next one. UserID and Author
+// are whoever made the change.
+type Revision struct {
+	SnippetID int
+	Number    int
+	Title     string
+	Content   string
+	UserID    int
+	Author    string
+	Created   time.Time
+}
+
+// revisionSelect is the start of every query which reads revisions, shared by
+// the SQL backends like snippetSelect.
+const revisionSelect = `SELECT r.snippet_id, r.revision, r.title, r.content, r.user_id, u.name, r.created
+FROM snippet_revisions AS r LEFT JOIN users AS u ON u.id = r.user_id`
+
+// revisionListSelect is revisionSelect without the content, for listing the
+// history of a snippet. The empty string keeps the columns the same so
+// scanRevision() works for both.
+const revisionListSelect = `SELECT r.snippet_id, r.revision, r.title, '', r.user_id, u.name, r.created
+FROM snippet_revisions AS r LEFT JOIN users AS u ON u.id = r.user_id`
+
+// scanRevision copies the columns listed in revisionSelect into a Revision.
+func scanRevision(row rowScanner) (Revision, error) {
+	var (
+		r      Revision
+		userID sql.NullInt64
+		author sql.NullString
+	)
+
+	err := row.Scan(&r.SnippetID, &r.Number, &r.Title, &r.Content, &userID, &author, &r.Created)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	r.UserID = int(userID.Int64)
+	r.Author = author.String
+
+	return r, nil
+}
+
+// scanRevisions reads every row of a revisionListSelect query and closes rows.
+func scanRevisions(rows *sql.Rows) ([]Revision, error) {
+	defer rows.Close()
+
+	var revisions []Revision
+
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return revisions, nil
+}
+
+// checkEditable decides whether userID may edit a snippet, given the owner
+// read from the snippets table (err is the error from reading it).
+func checkEditable(owner sql.NullInt64, err error, userID int) error {
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return timeoutErr(err)
+	}
+	if userID == 0 || !owner.Valid || int(owner.Int64) != userID {
+		return ErrNotOwner
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// maxSearchTerms caps how many words of a query are used, so a pasted wall
+// of text doesn't turn into a huge full-text query.
+const maxSearchTerms = 8
+
+// SearchTerms splits a search query into the words Search() looks for:
+// runs of letters and digits, lower-cased, without duplicates. Everything
+// else (quotes, operators like + and -) is dropped, so a query can never be
+// mistaken for full-text syntax by the database.
+//
+// Search() matches snippets containing every term, with each term matching
+// the start of a word, so "ser" finds "server".
+func SearchTerms(query string) []string {
+	var terms []string
+	for _, w := range searchWords(query) {
+		if len(terms) == maxSearchTerms {
+			break
+		}
+		if !slices.Contains(terms, w) {
+			terms = append(terms, w)
+		}
+	}
+
+	return terms
+}
+
+// searchWords splits text into lower-cased runs of letters and digits, the
+// same way the full-text indexes see it (near enough).
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// mysqlBooleanQuery turns terms into a MySQL boolean mode query where every
+// term is required and matches as a prefix: +foo* +bar*.
+func mysqlBooleanQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = "+" + t + "*"
+	}
+	return strings.Join(parts, " ")
+}
+
+// sqliteMatchQuery turns terms into an FTS5 query: "foo"* "bar"*. Quoting
+// each term keeps words like AND, OR and NOT from being read as operators.
+func sqliteMatchQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = `"` + t + `"*`
+	}
+	return strings.Join(parts, " ")
+}
+
+// postgresTSQuery turns terms into a to_tsquery() query: foo:* & bar:*.
+func postgresTSQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
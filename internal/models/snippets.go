@@ -1,119 +1,554 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"slices"
 	"time"
 )
 
 // Define a Snippet type to hold the data for an individual snippet. fields of the struct correspond to the fields in our MySQL snippets table
+// UserID is the owner of the snippet and Author their name (joined from the
+// users table). Both are zero for snippets posted before user accounts existed.
+// Tags are in alphabetical order. Language is the Chroma name of the language
+// the content is highlighted as, or "" for plain text. Format is how the
+// content is shown: FormatPlain or FormatMarkdown. TokenID is the API token
+// the snippet was created with, or 0 if it was created in a browser (or the
+// token has since been revoked).
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
-	Created time.Time
-	Expires time.Time
+	ID       int
+	Title    string
+	Content  string
+	Created  time.Time
+	Expires  time.Time
+	UserID   int
+	Author   string
+	Tags     []string
+	Language string
+	Format   string
+	TokenID  int
+}
+
+// The formats a snippet's content can be in.
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+// SnippetInput holds what a user chooses when they create or edit a snippet.
+// Expires is the number of days until the snippet expires; Update() also
+// takes 0, to keep the snippet's current expiry. Tags should already be
+// cleaned up (lower case, no duplicates); the models store them as they are.
+// TokenID is the API token the request was authenticated with, if any; it's
+// recorded by Insert() and ignored by Update().
+type SnippetInput struct {
+	Title    string
+	Content  string
+	Expires  int
+	Tags     []string
+	Language string
+	Format   string
+	TokenID  int
+}
+
+// SnippetStore describes the methods our handlers need from a snippet model.
+// The application struct holds a SnippetStore rather than a concrete
+// *SnippetModel, so the handlers don't care whether the snippets live in MySQL
+// or somewhere else (like the in-memory MemorySnippetModel).
+//
+// Every method takes a context.Context, so a query is abandoned as soon as the
+// client goes away or the model's timeout runs out (in which case the method
+// returns ErrTimeout).
+//
+// Methods which change a snippet take the id of the user making the change,
+// and return ErrNotOwner if the snippet belongs to someone else.
+type SnippetStore interface {
+	Insert(ctx context.Context, in SnippetInput, userID int) (int, error)
+	Get(ctx context.Context, id int) (Snippet, error)
+	Latest(ctx context.Context) ([]Snippet, error)
+	ByUser(ctx context.Context, userID int, limit, offset int) ([]Snippet, error)
+	ByTag(ctx context.Context, tag string, limit, offset int) ([]Snippet, error)
+	ListAfter(ctx context.Context, cursor Cursor, limit int) ([]Snippet, error)
+	ListBefore(ctx context.Context, cursor Cursor, limit int) ([]Snippet, error)
+	Search(ctx context.Context, query string, limit, offset int) ([]Snippet, error)
+	Update(ctx context.Context, id int, userID int, in SnippetInput) error
+	Revisions(ctx context.Context, id int) ([]Revision, error)
+	Revision(ctx context.Context, id int, number int) (Revision, error)
+	Delete(ctx context.Context, id int, userID int) error
+	DeleteExpired(ctx context.Context, limit int) (int, error)
+}
+
+// snippetSelect is the start of every query which reads whole snippets. It's
+// the same for every SQL backend, and lists the columns explicitly so
+// scanSnippet() doesn't depend on the column order of the table. The LEFT JOIN
+// keeps snippets which have no owner.
+const snippetSelect = `SELECT s.id, s.title, s.content, s.created, s.expires, s.user_id, u.name, s.language, s.format, s.token_id
+FROM snippets AS s LEFT JOIN users AS u ON u.id = s.user_id`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSnippet copies the columns listed in snippetSelect into a Snippet.
+func scanSnippet(row rowScanner) (Snippet, error) {
+	var (
+		s       Snippet
+		userID  sql.NullInt64
+		author  sql.NullString
+		tokenID sql.NullInt64
+	)
+
+	// user_id and name are NULL for snippets without an owner
+	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires, &userID, &author, &s.Language, &s.Format, &tokenID)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	s.UserID = int(userID.Int64)
+	s.Author = author.String
+	s.TokenID = int(tokenID.Int64)
+
+	return s, nil
+}
+
+// scanSnippets reads every row of a snippetSelect query and closes rows.
+func scanSnippets(rows *sql.Rows) ([]Snippet, error) {
+	// defer row.close() to ensure sql.Rows resultset always properly closed
+	defer rows.Close()
+
+	// initialize an empty slice to hold the snippet structs
+	var snippets []Snippet
+
+	// rows.Next to iterate through the rows in the resultset lazily
+	// any errors whole iterating will not terminate the loop, that is why we have to do a final error check after loop completion
+	for rows.Next() {
+		// rows.Scan() to copy the values from each field in the row
+		s, err := scanSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		// Append it to the slice of snippets
+		snippets = append(snippets, s)
+	}
+
+	// Very important - Once rows.next has completed iterating over the result set, check for any error during iteration
+	// idiomatic go way:
+	/**
+	Call rows.Err()
+	Assign its result to the existing err variable
+	Immediately check it
+	*/
+	if err := rows.Err(); err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	// if everything went ok return the snippets
+	return snippets, nil
+}
+
+// whyUnchanged works out why a DELETE of snippet id by userID matched no
+// rows, given the result of a Get() for the same snippet: it's either gone
+// (ErrNoRecord) or owned by somebody else (ErrNotOwner).
+func whyUnchanged(s Snippet, err error, userID int) error {
+	if err != nil {
+		return err
+	}
+	if userID == 0 || s.UserID != userID {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+// nullID turns an id into a value for a nullable foreign key column like
+// user_id or token_id, with 0 (none) stored as NULL.
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
 }
 
 // Define a SnippetModel type which wraps a sql.DB connection pool.
 // all snippet-related queries go through this model.
 // *sql.DB is a connection pool, not a single connection.
+// Timeout limits how long each query may run; zero means no limit.
 type SnippetModel struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Timeout time.Duration
 }
 
 // insert into snippets table
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
+// userID is the owner of the new snippet.
+func (m *SnippetModel) Insert(ctx context.Context, in SnippetInput, userID int) (int, error) {
+	// Derive a context which is cancelled after m.Timeout, and make sure we
+	// release its resources when we return.
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	// The snippet and its first revision are written in one transaction, so
+	// there's never a snippet without any history. Rollback() does nothing
+	// once Commit() has succeeded.
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+	defer tx.Rollback()
+
 	// sql insert query. using backquotes to split the query into multiple lines
 	statement := `INSERT INTO snippets 
-    (title, content, created, expires)
-VALUES (?,?,UTC_TIMESTAMP(),DATE_ADD(UTC_TIMESTAMP(),INTERVAL ? DAY))`
-	// Use the Exec() method on the embedded connection pool to execute the statement.
-	result, err := m.DB.Exec(statement, title, content, expires)
+    (title, content, created, expires, user_id, language, format, token_id)
+VALUES (?,?,UTC_TIMESTAMP(),DATE_ADD(UTC_TIMESTAMP(),INTERVAL ? DAY),?,?,?,?)`
+	// Use the ExecContext() method on the transaction to execute the statement.
+	result, err := tx.ExecContext(ctx, statement, in.Title, in.Content, in.Expires, nullID(userID), in.Language, in.Format, nullID(in.TokenID))
 	if err != nil {
-		return 0, err
+		return 0, timeoutErr(err)
 	}
 	// Use the LastInsertId() method on the result to get the ID of our newly inserted record in the snippets table.
 	id, err := result.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
+
+	_, err = tx.ExecContext(ctx, mysqlRecordRevision, nullID(userID), id)
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	err = mysqlTags.set(ctx, tx, int(id), in.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+
 	// The ID returned has the type int64, so we convert it to an int type before returning.
 	return int(id), nil
 }
 
+// mysqlRecordRevision copies the current title and content of a snippet into
+// its next revision. The parameters are the id of the user making the change
+// and the snippet id.
+const mysqlRecordRevision = `INSERT INTO snippet_revisions (snippet_id, revision, title, content, user_id, created)
+SELECT s.id, (SELECT COALESCE(MAX(r.revision), 0) + 1 FROM snippet_revisions AS r WHERE r.snippet_id = s.id),
+    s.title, s.content, ?, UTC_TIMESTAMP()
+FROM snippets AS s WHERE s.id = ?`
+
 // This will return a specific snippet based on its id.
-func (m *SnippetModel) Get(id int) (Snippet, error) {
-	statement := `SELECT * FROM snippets WHERE expires > UTC_TIMESTAMP() and id = ?`
+func (m *SnippetModel) Get(ctx context.Context, id int) (Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE s.expires > UTC_TIMESTAMP() and s.id = ?`
 
-	// QueryRow - to query one record
-	row := m.DB.QueryRow(statement, id)
+	// QueryRowContext - to query one record
+	row := m.DB.QueryRowContext(ctx, statement, id)
 
 	// Initialize a new zeroed Snippet struct.
 	/* s := Snippet{} vs var s Snippet
 	* s := Snippet{} -> creates an empty snippet, even if no records returned. When you call GET on an invalid id, in Addition to 404, u will also see empty struct.
 	* var s Snippet -> doesn’t create a structure until there are valid records to populate. When you call GET on an invalid id, you will get only a 404 and not see an empty structure.
 	 */
-	var s Snippet
-
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	s, err := scanSnippet(row)
 	if err != nil {
 		// check if query return no records error
 		if errors.Is(err, sql.ErrNoRows) {
 			return Snippet{}, ErrNoRecord
 		} else {
-			return Snippet{}, err
+			return Snippet{}, timeoutErr(err)
 		}
 	}
 	// if everything went ok, return filled snippet struct
+	err = mysqlTags.loadOne(ctx, m.DB, &s)
+	if err != nil {
+		return Snippet{}, err
+	}
+
 	return s, nil
 
 }
 
 // This will return the 10 most recently created snippets.
-func (m *SnippetModel) Latest() ([]Snippet, error) {
-	statement := "SELECT * FROM snippets where expires > UTC_TIMESTAMP() ORDER BY created DESC LIMIT 10"
+func (m *SnippetModel) Latest(ctx context.Context) ([]Snippet, error) {
+	// the deferred cancel() runs after we've finished reading the rows
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + " where s.expires > UTC_TIMESTAMP() ORDER BY s.created DESC LIMIT 10"
 
-	rows, err := m.DB.Query(statement)
+	rows, err := m.DB.QueryContext(ctx, statement)
 
+	// defer should come always after error check, otherwise, if Query() returns an error, you'll get a panic trying to close a nil resultset
 	if err != nil {
-		return nil, err
+		return nil, timeoutErr(err)
 	}
 
-	// defer row.close() to ensure sql.Rows resultset always properly closed
-	// defer should come always after error check, otherwise, if Query() returns an error, you'll get a panic trying to close a nil resultset
-	defer rows.Close()
+	return mysqlTags.scanSnippets(ctx, m.DB, rows)
+}
 
-	// initialize an empty slice to hold the snippet structs
-	var snippets []Snippet
+// ByUser returns the snippets owned by a user which haven't expired, newest
+// first. limit and offset pick the page.
+func (m *SnippetModel) ByUser(ctx context.Context, userID int, limit, offset int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
 
-	// rows.Next to iterate through the rows in the resultset lazily
-	// any errors whole iterating will not terminate the loop, that is why we have to do a final error check after loop completion
-	for rows.Next() {
-		// create a new zeroed value snippet struct
-		var s Snippet
-		// rows.Scan() to copy the values from each field in the row
-		err := rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	statement := snippetSelect + ` WHERE s.user_id = ? AND s.expires > UTC_TIMESTAMP()
+ORDER BY s.created DESC, s.id DESC LIMIT ? OFFSET ?`
+
+	rows, err := m.DB.QueryContext(ctx, statement, userID, limit, offset)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return mysqlTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// ByTag returns the unexpired snippets with a tag, newest first. limit and
+// offset pick the page.
+func (m *SnippetModel) ByTag(ctx context.Context, tag string, limit, offset int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + tagJoin + ` WHERE t.name = ? AND s.expires > UTC_TIMESTAMP()
+ORDER BY s.created DESC, s.id DESC LIMIT ? OFFSET ?`
 
+	rows, err := m.DB.QueryContext(ctx, statement, tag, limit, offset)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return mysqlTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// ListAfter returns up to limit unexpired snippets which come after cursor in
+// the list of all snippets (newest first), or from the start of the list for
+// the zero Cursor. It's the next page of a keyset-paginated listing.
+//
+// The condition is the spelled-out form of (created, id) < (?, ?), which
+// MySQL is better at using the idx_snippets_created_id index for.
+func (m *SnippetModel) ListAfter(ctx context.Context, cursor Cursor, limit int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if cursor.IsZero() {
+		statement := snippetSelect + ` WHERE s.expires > UTC_TIMESTAMP()
+ORDER BY s.created DESC, s.id DESC LIMIT ?`
+
+		rows, err := m.DB.QueryContext(ctx, statement, limit)
 		if err != nil {
-			return nil, err
+			return nil, timeoutErr(err)
 		}
-		// Append it to the slice of snippets
-		snippets = append(snippets, s)
+		return mysqlTags.scanSnippets(ctx, m.DB, rows)
+	}
+
+	statement := snippetSelect + ` WHERE (s.created < ? OR (s.created = ? AND s.id < ?))
+AND s.expires > UTC_TIMESTAMP()
+ORDER BY s.created DESC, s.id DESC LIMIT ?`
 
+	rows, err := m.DB.QueryContext(ctx, statement, cursor.Created, cursor.Created, cursor.ID, limit)
+	if err != nil {
+		return nil, timeoutErr(err)
 	}
 
-	// Very important - Once rows.next has completed iterating over the result set, check for any error during iteration
-	// idiomatic go way:
-	/**
-	Call rows.Err()
-	Assign its result to the existing err variable
-	Immediately check it
-	*/
-	if err := rows.Err(); err != nil {
+	return mysqlTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// ListBefore returns up to limit unexpired snippets which come just before
+// cursor in the list of all snippets - the previous page. They're read
+// oldest first, so the LIMIT keeps the ones nearest the cursor, and then
+// put back into newest first order.
+func (m *SnippetModel) ListBefore(ctx context.Context, cursor Cursor, limit int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE (s.created > ? OR (s.created = ? AND s.id > ?))
+AND s.expires > UTC_TIMESTAMP()
+ORDER BY s.created ASC, s.id ASC LIMIT ?`
+
+	rows, err := m.DB.QueryContext(ctx, statement, cursor.Created, cursor.Created, cursor.ID, limit)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	snippets, err := mysqlTags.scanSnippets(ctx, m.DB, rows)
+	if err != nil {
 		return nil, err
 	}
 
-	// if everything went ok return the snippets
+	slices.Reverse(snippets)
 	return snippets, nil
 }
+
+// Search returns the unexpired snippets which contain every word of query
+// (see SearchTerms), best match first. limit and offset pick the page. A
+// query without any words matches nothing.
+//
+// It uses the FULLTEXT index on title and content in boolean mode, which
+// lets us require every term and match prefixes. The MATCH() in the ORDER BY
+// is the relevance score; MySQL only works it out once.
+func (m *SnippetModel) Search(ctx context.Context, query string, limit, offset int) ([]Snippet, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE MATCH(s.title, s.content) AGAINST (? IN BOOLEAN MODE)
+AND s.expires > UTC_TIMESTAMP()
+ORDER BY MATCH(s.title, s.content) AGAINST (? IN BOOLEAN MODE) DESC, s.created DESC, s.id DESC
+LIMIT ? OFFSET ?`
+
+	q := mysqlBooleanQuery(terms)
+	rows, err := m.DB.QueryContext(ctx, statement, q, q, limit, offset)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return mysqlTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// Delete deletes a snippet owned by userID. The owner check is part of the
+// DELETE itself, so nobody else's snippet can be deleted even if a handler
+// forgets to check. It returns ErrNoRecord if the snippet doesn't exist (or
+// has expired) and ErrNotOwner if it belongs to somebody else.
+func (m *SnippetModel) Delete(ctx context.Context, id int, userID int) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `DELETE FROM snippets WHERE id = ? AND user_id = ? AND expires > UTC_TIMESTAMP()`
+
+	result, err := m.DB.ExecContext(ctx, statement, id, userID)
+	if err != nil {
+		return timeoutErr(err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// Nothing was deleted, so work out why.
+	s, err := m.Get(ctx, id)
+	return whyUnchanged(s, err, userID)
+}
+
+// Update changes the title and content of a snippet owned by userID, and
+// makes it expire in.Expires days from now, or keeps its current expiry if
+// in.Expires is 0. If the title or content changed a new revision is
+// recorded, in the same transaction. It returns
+// ErrNoRecord if the snippet doesn't exist (or has expired) and ErrNotOwner
+// if it belongs to somebody else.
+func (m *SnippetModel) Update(ctx context.Context, id int, userID int, in SnippetInput) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return timeoutErr(err)
+	}
+	defer tx.Rollback()
+
+	// FOR UPDATE locks the snippet until we commit, so two edits at once
+	// can't both pick the same revision number.
+	var (
+		owner                sql.NullInt64
+		oldTitle, oldContent string
+	)
+	err = tx.QueryRowContext(ctx, `SELECT user_id, title, content FROM snippets
+WHERE id = ? AND expires > UTC_TIMESTAMP() FOR UPDATE`, id).Scan(&owner, &oldTitle, &oldContent)
+	err = checkEditable(owner, err, userID)
+	if err != nil {
+		return err
+	}
+
+	statement := `UPDATE snippets SET title = ?, content = ?,
+    expires = CASE WHEN ? = 0 THEN expires ELSE DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY) END,
+    language = ?, format = ?
+WHERE id = ?`
+
+	_, err = tx.ExecContext(ctx, statement, in.Title, in.Content, in.Expires, in.Expires, in.Language, in.Format, id)
+	if err != nil {
+		return timeoutErr(err)
+	}
+
+	// Only keeping the snippet around for longer isn't worth a revision.
+	if in.Title != oldTitle || in.Content != oldContent {
+		_, err = tx.ExecContext(ctx, mysqlRecordRevision, nullID(userID), id)
+		if err != nil {
+			return timeoutErr(err)
+		}
+	}
+
+	// Tags aren't part of the history, so they're saved either way.
+	err = mysqlTags.set(ctx, tx, id, in.Tags)
+	if err != nil {
+		return err
+	}
+
+	return timeoutErr(tx.Commit())
+}
+
+// Revisions returns the history of a snippet, newest first, without the
+// content of each revision.
+func (m *SnippetModel) Revisions(ctx context.Context, id int) ([]Revision, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := revisionListSelect + ` WHERE r.snippet_id = ? ORDER BY r.revision DESC`
+
+	rows, err := m.DB.QueryContext(ctx, statement, id)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return scanRevisions(rows)
+}
+
+// Revision returns one revision of a snippet, or ErrNoRecord if there's no
+// such revision.
+func (m *SnippetModel) Revision(ctx context.Context, id int, number int) (Revision, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := revisionSelect + ` WHERE r.snippet_id = ? AND r.revision = ?`
+
+	r, err := scanRevision(m.DB.QueryRowContext(ctx, statement, id, number))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Revision{}, ErrNoRecord
+		}
+		return Revision{}, timeoutErr(err)
+	}
+
+	return r, nil
+}
+
+// DeleteExpired deletes up to limit expired snippets and returns how many were
+// deleted. Capping each call keeps the DELETE from locking the table for long;
+// callers keep going until it returns fewer than limit.
+func (m *SnippetModel) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	// MySQL supports LIMIT directly on a DELETE statement.
+	statement := `DELETE FROM snippets WHERE expires <= UTC_TIMESTAMP() LIMIT ?`
+
+	result, err := m.DB.ExecContext(ctx, statement, limit)
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	// RowsAffected() tells us how many rows the DELETE removed.
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
@@ -0,0 +1,467 @@
+package models
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemorySnippetModel is an in-memory implementation of SnippetStore. It follows
+// the same rules as the MySQL SnippetModel (UTC timestamps truncated to the
+// second, expired snippets are never returned, Latest() returns at most 10
+// snippets newest first), so the whole web app can run without a database.
+// The zero value is ready to use. Nothing here ever blocks, so the context is
+// only checked to see whether the caller has already given up.
+//
+// Users is used to fill in the Author of each snippet; if it's nil snippets
+// are returned without one.
+type MemorySnippetModel struct {
+	Users *MemoryUserModel
+
+	mu        sync.RWMutex
+	snippets  map[int]Snippet
+	revisions map[int][]Revision // by snippet id, oldest first
+	lastID    int
+}
+
+// Insert adds a new snippet and returns its id. ids start at 1, just like an
+// AUTO_INCREMENT column.
+func (m *MemorySnippetModel) Insert(ctx context.Context, in SnippetInput, userID int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// initialize the map on first use so the zero value works
+	if m.snippets == nil {
+		m.snippets = make(map[int]Snippet)
+	}
+
+	// UTC_TIMESTAMP() has second precision, so truncate to match.
+	created := time.Now().UTC().Truncate(time.Second)
+
+	m.lastID++
+	s := Snippet{
+		ID:      m.lastID,
+		Title:   in.Title,
+		Content: in.Content,
+		Created: created,
+		// same as DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY)
+		Expires:  created.AddDate(0, 0, in.Expires),
+		UserID:   userID,
+		Tags:     sortedTags(in.Tags),
+		Language: in.Language,
+		Format:   in.Format,
+		TokenID:  in.TokenID,
+	}
+	m.snippets[m.lastID] = s
+	m.recordRevision(s, userID, created)
+
+	return m.lastID, nil
+}
+
+// Get returns a specific snippet based on its id, or ErrNoRecord if it doesn't
+// exist or has expired.
+func (m *MemorySnippetModel) Get(ctx context.Context, id int) (Snippet, error) {
+	if err := ctx.Err(); err != nil {
+		return Snippet{}, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.snippets[id]
+	if !ok || !s.Expires.After(time.Now().UTC()) {
+		return Snippet{}, ErrNoRecord
+	}
+
+	return m.withAuthor(s), nil
+}
+
+// Latest returns the 10 most recently created snippets that haven't expired.
+func (m *MemorySnippetModel) Latest(ctx context.Context) ([]Snippet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snippets := m.live(func(Snippet) bool { return true })
+
+	// LIMIT 10
+	if len(snippets) > 10 {
+		snippets = snippets[:10]
+	}
+
+	return snippets, nil
+}
+
+// ByUser returns the snippets owned by a user which haven't expired, newest
+// first.
+func (m *MemorySnippetModel) ByUser(ctx context.Context, userID int, limit, offset int) ([]Snippet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snippets := m.live(func(s Snippet) bool { return s.UserID == userID })
+
+	return paginate(snippets, limit, offset)
+}
+
+// paginate does LIMIT ? OFFSET ? on a slice of snippets. Unlike the databases
+// it rejects a negative limit or offset, rather than slicing with it.
+func paginate(snippets []Snippet, limit, offset int) ([]Snippet, error) {
+	if limit < 0 || offset < 0 {
+		return nil, ErrInvalidPage
+	}
+
+	if offset >= len(snippets) {
+		return nil, nil
+	}
+	snippets = snippets[offset:]
+	if len(snippets) > limit {
+		snippets = snippets[:limit]
+	}
+
+	return snippets, nil
+}
+
+// ByTag returns the unexpired snippets with a tag, newest first.
+func (m *MemorySnippetModel) ByTag(ctx context.Context, tag string, limit, offset int) ([]Snippet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snippets := m.live(func(s Snippet) bool { return slices.Contains(s.Tags, tag) })
+
+	return paginate(snippets, limit, offset)
+}
+
+// ListAfter returns up to limit unexpired snippets which come after cursor in
+// the list of all snippets, newest first.
+func (m *MemorySnippetModel) ListAfter(ctx context.Context, cursor Cursor, limit int) ([]Snippet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snippets := m.live(func(s Snippet) bool {
+		return cursor.IsZero() || cursorLess(s.Cursor(), cursor)
+	})
+
+	if len(snippets) > limit {
+		snippets = snippets[:limit]
+	}
+
+	return snippets, nil
+}
+
+// ListBefore returns up to limit unexpired snippets which come just before
+// cursor in the list of all snippets, newest first.
+func (m *MemorySnippetModel) ListBefore(ctx context.Context, cursor Cursor, limit int) ([]Snippet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snippets := m.live(func(s Snippet) bool {
+		return cursorLess(cursor, s.Cursor())
+	})
+
+	// keep the ones nearest the cursor, at the end of the list
+	if len(snippets) > limit {
+		snippets = snippets[len(snippets)-limit:]
+	}
+
+	return snippets, nil
+}
+
+// cursorLess reports whether a comes before b in (created, id) order, like
+// the SQL (created, id) < (?, ?).
+func cursorLess(a, b Cursor) bool {
+	if a.Created.Equal(b.Created) {
+		return a.ID < b.ID
+	}
+	return a.Created.Before(b.Created)
+}
+
+// Search returns the unexpired snippets containing a word starting with each
+// term of query, like the SQL versions. Snippets matching more terms in their
+// title come first, then the newest.
+func (m *MemorySnippetModel) Search(ctx context.Context, query string, limit, offset int) ([]Snippet, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snippets := m.live(func(s Snippet) bool {
+		return matchesAll(searchWords(s.Title+" "+s.Content), terms)
+	})
+
+	// Score each snippet once, by how many terms its title matches, rather
+	// than in every comparison of the sort.
+	type scored struct {
+		snippet Snippet
+		score   int
+	}
+	results := make([]scored, len(snippets))
+	for i, s := range snippets {
+		words := searchWords(s.Title)
+		results[i].snippet = s
+		for _, t := range terms {
+			if matchesAll(words, []string{t}) {
+				results[i].score++
+			}
+		}
+	}
+
+	// a stable sort keeps the newest first among equal scores
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+	for i, r := range results {
+		snippets[i] = r.snippet
+	}
+
+	return paginate(snippets, limit, offset)
+}
+
+// matchesAll reports whether every term is the start of one of words.
+func matchesAll(words, terms []string) bool {
+	for _, t := range terms {
+		found := false
+		for _, w := range words {
+			if strings.HasPrefix(w, t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Delete deletes a snippet owned by userID, returning ErrNoRecord if it
+// doesn't exist (or has expired) and ErrNotOwner if it belongs to somebody
+// else.
+func (m *MemorySnippetModel) Delete(ctx context.Context, id int, userID int) error {
+	if err := ctx.Err(); err != nil {
+		return timeoutErr(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.snippets[id]
+	switch {
+	case !ok || !s.Expires.After(time.Now().UTC()):
+		return ErrNoRecord
+	case s.UserID == 0 || s.UserID != userID:
+		return ErrNotOwner
+	}
+
+	// ON DELETE CASCADE
+	delete(m.snippets, id)
+	delete(m.revisions, id)
+	return nil
+}
+
+// Update changes the title and content of a snippet owned by userID, and
+// makes it expire in.Expires days from now, or keeps its current expiry if
+// in.Expires is 0.
+func (m *MemorySnippetModel) Update(ctx context.Context, id int, userID int, in SnippetInput) error {
+	if err := ctx.Err(); err != nil {
+		return timeoutErr(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	s, ok := m.snippets[id]
+	switch {
+	case !ok || !s.Expires.After(now):
+		return ErrNoRecord
+	case s.UserID == 0 || s.UserID != userID:
+		return ErrNotOwner
+	}
+
+	changed := s.Title != in.Title || s.Content != in.Content
+
+	s.Title = in.Title
+	s.Content = in.Content
+	if in.Expires != 0 {
+		s.Expires = now.AddDate(0, 0, in.Expires)
+	}
+	s.Tags = sortedTags(in.Tags)
+	s.Language = in.Language
+	s.Format = in.Format
+	m.snippets[id] = s
+
+	if changed {
+		m.recordRevision(s, userID, now)
+	}
+
+	return nil
+}
+
+// recordRevision appends the current title and content of s to its history.
+// The caller must hold m.mu for writing.
+func (m *MemorySnippetModel) recordRevision(s Snippet, userID int, created time.Time) {
+	if m.revisions == nil {
+		m.revisions = make(map[int][]Revision)
+	}
+
+	m.revisions[s.ID] = append(m.revisions[s.ID], Revision{
+		SnippetID: s.ID,
+		Number:    len(m.revisions[s.ID]) + 1,
+		Title:     s.Title,
+		Content:   s.Content,
+		UserID:    userID,
+		Created:   created,
+	})
+}
+
+// Revisions returns the history of a snippet, newest first, without the
+// content of each revision.
+func (m *MemorySnippetModel) Revisions(ctx context.Context, id int) ([]Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.revisions[id]
+	revisions := make([]Revision, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		r := m.revisionWithAuthor(history[i])
+		r.Content = ""
+		revisions = append(revisions, r)
+	}
+
+	return revisions, nil
+}
+
+// Revision returns one revision of a snippet, or ErrNoRecord if there's no
+// such revision.
+func (m *MemorySnippetModel) Revision(ctx context.Context, id int, number int) (Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return Revision{}, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.revisions[id]
+	if number < 1 || number > len(history) {
+		return Revision{}, ErrNoRecord
+	}
+
+	return m.revisionWithAuthor(history[number-1]), nil
+}
+
+// revisionWithAuthor fills in r.Author from m.Users.
+func (m *MemorySnippetModel) revisionWithAuthor(r Revision) Revision {
+	if m.Users != nil && r.UserID != 0 {
+		r.Author = m.Users.name(r.UserID)
+	}
+	return r
+}
+
+// live returns the unexpired snippets matching keep, newest first. The
+// caller must hold m.mu.
+func (m *MemorySnippetModel) live(keep func(Snippet) bool) []Snippet {
+	now := time.Now().UTC()
+
+	var snippets []Snippet
+	for _, s := range m.snippets {
+		if s.Expires.After(now) && keep(s) {
+			snippets = append(snippets, m.withAuthor(s))
+		}
+	}
+
+	// ORDER BY created DESC - fall back to the id so snippets created in the
+	// same second come out in a stable order.
+	sort.Slice(snippets, func(i, j int) bool {
+		if snippets[i].Created.Equal(snippets[j].Created) {
+			return snippets[i].ID > snippets[j].ID
+		}
+		return snippets[i].Created.After(snippets[j].Created)
+	})
+
+	return snippets
+}
+
+// sortedTags returns a sorted copy of tags, like the ORDER BY t.name the SQL
+// models use. Copying means the caller can't change a stored snippet's tags
+// by changing its slice afterwards.
+func sortedTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	tags = slices.Clone(tags)
+	slices.Sort(tags)
+	return tags
+}
+
+// withAuthor fills in s.Author from m.Users.
+func (m *MemorySnippetModel) withAuthor(s Snippet) Snippet {
+	if m.Users != nil && s.UserID != 0 {
+		s.Author = m.Users.name(s.UserID)
+	}
+	return s
+}
+
+// DeleteExpired deletes up to limit expired snippets and returns how many were
+// deleted.
+func (m *MemorySnippetModel) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	deleted := 0
+
+	for id, s := range m.snippets {
+		if deleted >= limit {
+			break
+		}
+		if !s.Expires.After(now) {
+			delete(m.snippets, id)
+			delete(m.revisions, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
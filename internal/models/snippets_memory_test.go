@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// newPagingTestModel returns a memory model holding three snippets by user
+// 1, each tagged "go" and containing "hello", for the paging tests.
+func newPagingTestModel(t *testing.T) *MemorySnippetModel {
+	t.Helper()
+
+	m := &MemorySnippetModel{}
+	for _, title := range []string{"one", "two", "three"} {
+		_, err := m.Insert(t.Context(), SnippetInput{Title: title, Content: "hello", Expires: 7, Tags: []string{"go"}}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+// testPaging checks LIMIT/OFFSET paging in one of the memory model's
+// listings of the snippets from newPagingTestModel. They have to guard
+// against offsets that would be a bad slice index.
+func testPaging(t *testing.T, list func(limit, offset int) ([]Snippet, error)) {
+	tests := []struct {
+		name          string
+		limit, offset int
+		wantLen       int
+		wantErr       error
+	}{
+		{name: "First page", limit: 2, offset: 0, wantLen: 2},
+		{name: "Last page", limit: 2, offset: 2, wantLen: 1},
+		{name: "Past the end", limit: 2, offset: 3, wantLen: 0},
+		{name: "Far past the end", limit: 2, offset: 1 << 62, wantLen: 0},
+		{name: "Negative offset", limit: 2, offset: -12, wantErr: ErrInvalidPage},
+		{name: "Negative limit", limit: -1, offset: 0, wantErr: ErrInvalidPage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippets, err := list(tt.limit, tt.offset)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v; want %v", err, tt.wantErr)
+			}
+			if len(snippets) != tt.wantLen {
+				t.Errorf("got %d snippets; want %d", len(snippets), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestMemorySnippetModelByUser(t *testing.T) {
+	m := newPagingTestModel(t)
+	testPaging(t, func(limit, offset int) ([]Snippet, error) {
+		return m.ByUser(t.Context(), 1, limit, offset)
+	})
+}
+
+func TestMemorySnippetModelSearch(t *testing.T) {
+	m := newPagingTestModel(t)
+	testPaging(t, func(limit, offset int) ([]Snippet, error) {
+		return m.Search(t.Context(), "hello", limit, offset)
+	})
+}
+
+func TestMemorySnippetModelByTag(t *testing.T) {
+	m := newPagingTestModel(t)
+	testPaging(t, func(limit, offset int) ([]Snippet, error) {
+		return m.ByTag(t.Context(), "go", limit, offset)
+	})
+}
+
+// TestMemorySnippetModelSearchRanking checks that snippets matching more
+// terms in their title come first, and the newest first among equals.
+func TestMemorySnippetModelSearchRanking(t *testing.T) {
+	m := &MemorySnippetModel{}
+	for _, s := range []SnippetInput{
+		{Title: "Notes", Content: "go channels", Expires: 7},
+		{Title: "Go channels", Content: "select", Expires: 7},
+		{Title: "More notes", Content: "go channels", Expires: 7},
+		{Title: "Channels", Content: "go", Expires: 7},
+	} {
+		_, err := m.Insert(t.Context(), s, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snippets, err := m.Search(t.Context(), "go channels", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, s := range snippets {
+		got = append(got, s.Title)
+	}
+	want := []string{"Go channels", "Channels", "More notes", "Notes"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
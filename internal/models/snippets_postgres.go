@@ -0,0 +1,355 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+)
+
+// PostgresSnippetModel is the PostgreSQL version of SnippetModel. Postgres
+// differs from MySQL in a few ways that matter here:
+//   - placeholders are numbered ($1, $2...) instead of ?
+//   - there's no UTC_TIMESTAMP() or DATE_ADD(), so we use
+//     now() AT TIME ZONE 'UTC' and add an interval to it
+//   - the driver doesn't support LastInsertId(), so Insert() asks for the new
+//     id with RETURNING id instead.
+//
+// The created and expires columns are plain TIMESTAMP (without time zone)
+// holding UTC, just like the MySQL DATETIME columns.
+type PostgresSnippetModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// insert into snippets table
+func (m *PostgresSnippetModel) Insert(ctx context.Context, in SnippetInput, userID int) (int, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	// Write the snippet and its first revision together, as in SnippetModel.
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+	defer tx.Rollback()
+
+	statement := `INSERT INTO snippets (title, content, created, expires, user_id, language, format, token_id)
+VALUES ($1, $2, now() AT TIME ZONE 'UTC', (now() AT TIME ZONE 'UTC') + make_interval(days => $3), $4, $5, $6, $7)
+RETURNING id`
+
+	// RETURNING gives us a row back, so we use QueryRow() rather than Exec().
+	var id int
+
+	err = tx.QueryRowContext(ctx, statement, in.Title, in.Content, in.Expires, nullID(userID), in.Language, in.Format, nullID(in.TokenID)).Scan(&id)
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	_, err = tx.ExecContext(ctx, postgresRecordRevision, nullID(userID), id)
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	err = postgresTags.set(ctx, tx, id, in.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	return id, nil
+}
+
+// postgresRecordRevision copies the current title and content of a snippet
+// into its next revision, like mysqlRecordRevision. $1 needs a cast because
+// Postgres can't work out its type from the SELECT list.
+const postgresRecordRevision = `INSERT INTO snippet_revisions (snippet_id, revision, title, content, user_id, created)
+SELECT s.id, (SELECT COALESCE(MAX(r.revision), 0) + 1 FROM snippet_revisions AS r WHERE r.snippet_id = s.id),
+    s.title, s.content, $1::integer, now() AT TIME ZONE 'UTC'
+FROM snippets AS s WHERE s.id = $2`
+
+// This will return a specific snippet based on its id.
+func (m *PostgresSnippetModel) Get(ctx context.Context, id int) (Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE s.expires > now() AT TIME ZONE 'UTC' AND s.id = $1`
+
+	s, err := scanSnippet(m.DB.QueryRowContext(ctx, statement, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Snippet{}, ErrNoRecord
+		}
+		return Snippet{}, timeoutErr(err)
+	}
+
+	err = postgresTags.loadOne(ctx, m.DB, &s)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	return s, nil
+}
+
+// This will return the 10 most recently created snippets.
+func (m *PostgresSnippetModel) Latest(ctx context.Context) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE s.expires > now() AT TIME ZONE 'UTC' ORDER BY s.created DESC LIMIT 10`
+
+	rows, err := m.DB.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return postgresTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// ByUser returns the snippets owned by a user which haven't expired, newest
+// first.
+func (m *PostgresSnippetModel) ByUser(ctx context.Context, userID int, limit, offset int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE s.user_id = $1 AND s.expires > now() AT TIME ZONE 'UTC'
+ORDER BY s.created DESC, s.id DESC LIMIT $2 OFFSET $3`
+
+	rows, err := m.DB.QueryContext(ctx, statement, userID, limit, offset)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return postgresTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// ByTag returns the unexpired snippets with a tag, newest first.
+func (m *PostgresSnippetModel) ByTag(ctx context.Context, tag string, limit, offset int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + tagJoin + ` WHERE t.name = $1 AND s.expires > now() AT TIME ZONE 'UTC'
+ORDER BY s.created DESC, s.id DESC LIMIT $2 OFFSET $3`
+
+	rows, err := m.DB.QueryContext(ctx, statement, tag, limit, offset)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return postgresTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// ListAfter returns the page of snippets after cursor, like the MySQL
+// version. Postgres handles the row comparison well, using the
+// idx_snippets_created_id index.
+func (m *PostgresSnippetModel) ListAfter(ctx context.Context, cursor Cursor, limit int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if cursor.IsZero() {
+		statement := snippetSelect + ` WHERE s.expires > now() AT TIME ZONE 'UTC'
+ORDER BY s.created DESC, s.id DESC LIMIT $1`
+
+		rows, err := m.DB.QueryContext(ctx, statement, limit)
+		if err != nil {
+			return nil, timeoutErr(err)
+		}
+		return postgresTags.scanSnippets(ctx, m.DB, rows)
+	}
+
+	statement := snippetSelect + ` WHERE (s.created, s.id) < ($1, $2) AND s.expires > now() AT TIME ZONE 'UTC'
+ORDER BY s.created DESC, s.id DESC LIMIT $3`
+
+	rows, err := m.DB.QueryContext(ctx, statement, cursor.Created, cursor.ID, limit)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return postgresTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// ListBefore returns the page of snippets before cursor, like the MySQL
+// version.
+func (m *PostgresSnippetModel) ListBefore(ctx context.Context, cursor Cursor, limit int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE (s.created, s.id) > ($1, $2) AND s.expires > now() AT TIME ZONE 'UTC'
+ORDER BY s.created ASC, s.id ASC LIMIT $3`
+
+	rows, err := m.DB.QueryContext(ctx, statement, cursor.Created, cursor.ID, limit)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	snippets, err := postgresTags.scanSnippets(ctx, m.DB, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(snippets)
+	return snippets, nil
+}
+
+// Search returns the unexpired snippets matching query, best match first,
+// like the MySQL version. It matches against the generated search column,
+// and ts_rank() scores each match using the title and content weights set
+// there.
+func (m *PostgresSnippetModel) Search(ctx context.Context, query string, limit, offset int) ([]Snippet, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE s.search @@ to_tsquery('simple', $1) AND s.expires > now() AT TIME ZONE 'UTC'
+ORDER BY ts_rank(s.search, to_tsquery('simple', $1)) DESC, s.created DESC, s.id DESC LIMIT $2 OFFSET $3`
+
+	rows, err := m.DB.QueryContext(ctx, statement, postgresTSQuery(terms), limit, offset)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return postgresTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// Delete deletes a snippet owned by userID, returning ErrNoRecord or
+// ErrNotOwner just like the MySQL version.
+func (m *PostgresSnippetModel) Delete(ctx context.Context, id int, userID int) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `DELETE FROM snippets WHERE id = $1 AND user_id = $2 AND expires > now() AT TIME ZONE 'UTC'`
+
+	result, err := m.DB.ExecContext(ctx, statement, id, userID)
+	if err != nil {
+		return timeoutErr(err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	s, err := m.Get(ctx, id)
+	return whyUnchanged(s, err, userID)
+}
+
+// Update changes the title and content of a snippet owned by userID, and
+// records a new revision if they changed, like SnippetModel.Update().
+func (m *PostgresSnippetModel) Update(ctx context.Context, id int, userID int, in SnippetInput) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return timeoutErr(err)
+	}
+	defer tx.Rollback()
+
+	// FOR UPDATE locks the snippet until we commit, so two edits at once
+	// can't both pick the same revision number.
+	var (
+		owner                sql.NullInt64
+		oldTitle, oldContent string
+	)
+	err = tx.QueryRowContext(ctx, `SELECT user_id, title, content FROM snippets
+WHERE id = $1 AND expires > now() AT TIME ZONE 'UTC' FOR UPDATE`, id).Scan(&owner, &oldTitle, &oldContent)
+	err = checkEditable(owner, err, userID)
+	if err != nil {
+		return err
+	}
+
+	statement := `UPDATE snippets SET title = $1, content = $2,
+    expires = CASE WHEN $3 = 0 THEN expires ELSE (now() AT TIME ZONE 'UTC') + make_interval(days => $3) END,
+    language = $4, format = $5
+WHERE id = $6`
+
+	_, err = tx.ExecContext(ctx, statement, in.Title, in.Content, in.Expires, in.Language, in.Format, id)
+	if err != nil {
+		return timeoutErr(err)
+	}
+
+	if in.Title != oldTitle || in.Content != oldContent {
+		_, err = tx.ExecContext(ctx, postgresRecordRevision, nullID(userID), id)
+		if err != nil {
+			return timeoutErr(err)
+		}
+	}
+
+	// Tags aren't part of the history, so they're saved either way.
+	err = postgresTags.set(ctx, tx, id, in.Tags)
+	if err != nil {
+		return err
+	}
+
+	return timeoutErr(tx.Commit())
+}
+
+// Revisions returns the history of a snippet, newest first, without the
+// content of each revision.
+func (m *PostgresSnippetModel) Revisions(ctx context.Context, id int) ([]Revision, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := revisionListSelect + ` WHERE r.snippet_id = $1 ORDER BY r.revision DESC`
+
+	rows, err := m.DB.QueryContext(ctx, statement, id)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return scanRevisions(rows)
+}
+
+// Revision returns one revision of a snippet, or ErrNoRecord if there's no
+// such revision.
+func (m *PostgresSnippetModel) Revision(ctx context.Context, id int, number int) (Revision, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := revisionSelect + ` WHERE r.snippet_id = $1 AND r.revision = $2`
+
+	r, err := scanRevision(m.DB.QueryRowContext(ctx, statement, id, number))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Revision{}, ErrNoRecord
+		}
+		return Revision{}, timeoutErr(err)
+	}
+
+	return r, nil
+}
+
+// DeleteExpired deletes up to limit expired snippets and returns how many were
+// deleted. There's no DELETE ... LIMIT here, so we pick the ids to delete
+// with a subquery instead.
+func (m *PostgresSnippetModel) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `DELETE FROM snippets WHERE id IN
+    (SELECT id FROM snippets WHERE expires <= now() AT TIME ZONE 'UTC' LIMIT $1)`
+
+	result, err := m.DB.ExecContext(ctx, statement, limit)
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
@@ -0,0 +1,361 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+)
+
+// SQLiteSnippetModel is the SQLite version of SnippetModel. SQLite has no
+// UTC_TIMESTAMP() or DATE_ADD(), so we use datetime('now') (which is always
+// UTC) and datetime('now', '+N days') instead. Both produce text in the
+// 'YYYY-MM-DD HH:MM:SS' format, which sorts and compares correctly as a string
+// and which the driver scans into a time.Time because the columns are declared
+// as DATETIME.
+type SQLiteSnippetModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// insert into snippets table
+func (m *SQLiteSnippetModel) Insert(ctx context.Context, in SnippetInput, userID int) (int, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	// Write the snippet and its first revision together, as in SnippetModel.
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+	defer tx.Rollback()
+
+	// the expiry modifier is built as '+N days', e.g. '+7 days'
+	statement := `INSERT INTO snippets
+    (title, content, created, expires, user_id, language, format, token_id)
+VALUES (?, ?, datetime('now'), datetime('now', '+' || ? || ' days'), ?, ?, ?, ?)`
+
+	result, err := tx.ExecContext(ctx, statement, in.Title, in.Content, in.Expires, nullID(userID), in.Language, in.Format, nullID(in.TokenID))
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	// SQLite supports LastInsertId() just like MySQL.
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, sqliteRecordRevision, nullID(userID), id)
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	err = sqliteTags.set(ctx, tx, int(id), in.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	return int(id), nil
+}
+
+// sqliteRecordRevision copies the current title and content of a snippet
+// into its next revision, like mysqlRecordRevision.
+const sqliteRecordRevision = `INSERT INTO snippet_revisions (snippet_id, revision, title, content, user_id, created)
+SELECT s.id, (SELECT COALESCE(MAX(r.revision), 0) + 1 FROM snippet_revisions AS r WHERE r.snippet_id = s.id),
+    s.title, s.content, ?, datetime('now')
+FROM snippets AS s WHERE s.id = ?`
+
+// This will return a specific snippet based on its id.
+func (m *SQLiteSnippetModel) Get(ctx context.Context, id int) (Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE s.expires > datetime('now') AND s.id = ?`
+
+	s, err := scanSnippet(m.DB.QueryRowContext(ctx, statement, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Snippet{}, ErrNoRecord
+		}
+		return Snippet{}, timeoutErr(err)
+	}
+
+	err = sqliteTags.loadOne(ctx, m.DB, &s)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	return s, nil
+}
+
+// This will return the 10 most recently created snippets.
+func (m *SQLiteSnippetModel) Latest(ctx context.Context) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE s.expires > datetime('now') ORDER BY s.created DESC LIMIT 10`
+
+	rows, err := m.DB.QueryContext(ctx, statement)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return sqliteTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// ByUser returns the snippets owned by a user which haven't expired, newest
+// first.
+func (m *SQLiteSnippetModel) ByUser(ctx context.Context, userID int, limit, offset int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE s.user_id = ? AND s.expires > datetime('now')
+ORDER BY s.created DESC, s.id DESC LIMIT ? OFFSET ?`
+
+	rows, err := m.DB.QueryContext(ctx, statement, userID, limit, offset)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return sqliteTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// ByTag returns the unexpired snippets with a tag, newest first.
+func (m *SQLiteSnippetModel) ByTag(ctx context.Context, tag string, limit, offset int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + tagJoin + ` WHERE t.name = ? AND s.expires > datetime('now')
+ORDER BY s.created DESC, s.id DESC LIMIT ? OFFSET ?`
+
+	rows, err := m.DB.QueryContext(ctx, statement, tag, limit, offset)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return sqliteTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// ListAfter returns the page of snippets after cursor, like the MySQL
+// version. SQLite stores created as text ('2006-01-02 15:04:05', from
+// datetime()), so the cursor's time is formatted the same way to compare
+// against it; the driver would otherwise add a time zone.
+func (m *SQLiteSnippetModel) ListAfter(ctx context.Context, cursor Cursor, limit int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	if cursor.IsZero() {
+		statement := snippetSelect + ` WHERE s.expires > datetime('now')
+ORDER BY s.created DESC, s.id DESC LIMIT ?`
+
+		rows, err := m.DB.QueryContext(ctx, statement, limit)
+		if err != nil {
+			return nil, timeoutErr(err)
+		}
+		return sqliteTags.scanSnippets(ctx, m.DB, rows)
+	}
+
+	statement := snippetSelect + ` WHERE (s.created, s.id) < (?, ?) AND s.expires > datetime('now')
+ORDER BY s.created DESC, s.id DESC LIMIT ?`
+
+	rows, err := m.DB.QueryContext(ctx, statement, sqliteTime(cursor.Created), cursor.ID, limit)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return sqliteTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// ListBefore returns the page of snippets before cursor, like the MySQL
+// version.
+func (m *SQLiteSnippetModel) ListBefore(ctx context.Context, cursor Cursor, limit int) ([]Snippet, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` WHERE (s.created, s.id) > (?, ?) AND s.expires > datetime('now')
+ORDER BY s.created ASC, s.id ASC LIMIT ?`
+
+	rows, err := m.DB.QueryContext(ctx, statement, sqliteTime(cursor.Created), cursor.ID, limit)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	snippets, err := sqliteTags.scanSnippets(ctx, m.DB, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(snippets)
+	return snippets, nil
+}
+
+// sqliteTime formats t the way datetime() does.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(time.DateTime)
+}
+
+// Search returns the unexpired snippets matching query, best match first,
+// like the MySQL version. It uses the snippets_fts index; bm25() scores each
+// match (lower is better) and the weights make a title match count ten times
+// as much as one in the content.
+func (m *SQLiteSnippetModel) Search(ctx context.Context, query string, limit, offset int) ([]Snippet, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := snippetSelect + ` JOIN snippets_fts ON snippets_fts.rowid = s.id
+WHERE snippets_fts MATCH ? AND s.expires > datetime('now')
+ORDER BY bm25(snippets_fts, 10.0, 1.0), s.created DESC, s.id DESC LIMIT ? OFFSET ?`
+
+	rows, err := m.DB.QueryContext(ctx, statement, sqliteMatchQuery(terms), limit, offset)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return sqliteTags.scanSnippets(ctx, m.DB, rows)
+}
+
+// Delete deletes a snippet owned by userID, returning ErrNoRecord or
+// ErrNotOwner just like the MySQL version.
+func (m *SQLiteSnippetModel) Delete(ctx context.Context, id int, userID int) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `DELETE FROM snippets WHERE id = ? AND user_id = ? AND expires > datetime('now')`
+
+	result, err := m.DB.ExecContext(ctx, statement, id, userID)
+	if err != nil {
+		return timeoutErr(err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	s, err := m.Get(ctx, id)
+	return whyUnchanged(s, err, userID)
+}
+
+// Update changes the title and content of a snippet owned by userID, and
+// records a new revision if they changed, like SnippetModel.Update().
+func (m *SQLiteSnippetModel) Update(ctx context.Context, id int, userID int, in SnippetInput) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return timeoutErr(err)
+	}
+	defer tx.Rollback()
+
+	// There's no SELECT ... FOR UPDATE in SQLite, but we only ever use one
+	// connection, so nothing else can write while this transaction is open.
+	var (
+		owner                sql.NullInt64
+		oldTitle, oldContent string
+	)
+	err = tx.QueryRowContext(ctx, `SELECT user_id, title, content FROM snippets
+WHERE id = ? AND expires > datetime('now')`, id).Scan(&owner, &oldTitle, &oldContent)
+	err = checkEditable(owner, err, userID)
+	if err != nil {
+		return err
+	}
+
+	statement := `UPDATE snippets SET title = ?, content = ?,
+    expires = CASE WHEN ? = 0 THEN expires ELSE datetime('now', '+' || ? || ' days') END,
+    language = ?, format = ?
+WHERE id = ?`
+
+	_, err = tx.ExecContext(ctx, statement, in.Title, in.Content, in.Expires, in.Expires, in.Language, in.Format, id)
+	if err != nil {
+		return timeoutErr(err)
+	}
+
+	if in.Title != oldTitle || in.Content != oldContent {
+		_, err = tx.ExecContext(ctx, sqliteRecordRevision, nullID(userID), id)
+		if err != nil {
+			return timeoutErr(err)
+		}
+	}
+
+	// Tags aren't part of the history, so they're saved either way.
+	err = sqliteTags.set(ctx, tx, id, in.Tags)
+	if err != nil {
+		return err
+	}
+
+	return timeoutErr(tx.Commit())
+}
+
+// Revisions returns the history of a snippet, newest first, without the
+// content of each revision.
+func (m *SQLiteSnippetModel) Revisions(ctx context.Context, id int) ([]Revision, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := revisionListSelect + ` WHERE r.snippet_id = ? ORDER BY r.revision DESC`
+
+	rows, err := m.DB.QueryContext(ctx, statement, id)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return scanRevisions(rows)
+}
+
+// Revision returns one revision of a snippet, or ErrNoRecord if there's no
+// such revision.
+func (m *SQLiteSnippetModel) Revision(ctx context.Context, id int, number int) (Revision, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := revisionSelect + ` WHERE r.snippet_id = ? AND r.revision = ?`
+
+	r, err := scanRevision(m.DB.QueryRowContext(ctx, statement, id, number))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Revision{}, ErrNoRecord
+		}
+		return Revision{}, timeoutErr(err)
+	}
+
+	return r, nil
+}
+
+// DeleteExpired deletes up to limit expired snippets and returns how many were
+// deleted. There's no DELETE ... LIMIT here, so we pick the ids to delete
+// with a subquery instead.
+func (m *SQLiteSnippetModel) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `DELETE FROM snippets WHERE id IN
+    (SELECT id FROM snippets WHERE expires <= datetime('now') LIMIT ?)`
+
+	result, err := m.DB.ExecContext(ctx, statement, limit)
+	if err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
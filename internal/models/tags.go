@@ -0,0 +1,143 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// tagStatements holds the SQL for reading and writing the tags of snippets,
+// which differs between databases only in its placeholders and in how a tag
+// is inserted if it doesn't exist yet. Each SQL snippet model uses one of
+// mysqlTags, sqliteTags and postgresTags.
+type tagStatements struct {
+	// insertTag adds a tag by name, doing nothing if it already exists.
+	insertTag string
+	// linkTag links a snippet (first parameter) to a tag by name (second).
+	linkTag string
+	// unlinkAll removes every tag from a snippet.
+	unlinkAll string
+	// placeholder returns the n'th (from 1) query placeholder.
+	placeholder func(n int) string
+}
+
+var mysqlTags = tagStatements{
+	// Setting name to itself turns the duplicate key error into a no-op,
+	// without INSERT IGNORE's habit of ignoring every other error too.
+	insertTag:   `INSERT INTO tags (name) VALUES (?) ON DUPLICATE KEY UPDATE name = name`,
+	linkTag:     `INSERT INTO snippet_tags (snippet_id, tag_id) SELECT ?, id FROM tags WHERE name = ?`,
+	unlinkAll:   `DELETE FROM snippet_tags WHERE snippet_id = ?`,
+	placeholder: func(int) string { return "?" },
+}
+
+var sqliteTags = tagStatements{
+	insertTag:   `INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING`,
+	linkTag:     `INSERT INTO snippet_tags (snippet_id, tag_id) SELECT ?, id FROM tags WHERE name = ?`,
+	unlinkAll:   `DELETE FROM snippet_tags WHERE snippet_id = ?`,
+	placeholder: func(int) string { return "?" },
+}
+
+var postgresTags = tagStatements{
+	insertTag: `INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`,
+	// $1 needs a cast, like in postgresRecordRevision.
+	linkTag:     `INSERT INTO snippet_tags (snippet_id, tag_id) SELECT $1::integer, id FROM tags WHERE name = $2`,
+	unlinkAll:   `DELETE FROM snippet_tags WHERE snippet_id = $1`,
+	placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+}
+
+// set replaces the tags of a snippet, as part of the transaction which
+// creates or updates it. Tags which are no longer used by any snippet are
+// left in the tags table; they just list no snippets.
+func (ts tagStatements) set(ctx context.Context, tx *sql.Tx, snippetID int, tags []string) error {
+	_, err := tx.ExecContext(ctx, ts.unlinkAll, snippetID)
+	if err != nil {
+		return timeoutErr(err)
+	}
+
+	for _, tag := range tags {
+		_, err = tx.ExecContext(ctx, ts.insertTag, tag)
+		if err != nil {
+			return timeoutErr(err)
+		}
+		_, err = tx.ExecContext(ctx, ts.linkTag, snippetID, tag)
+		if err != nil {
+			return timeoutErr(err)
+		}
+	}
+
+	return nil
+}
+
+// load fills in the Tags of each snippet, in alphabetical order, with one
+// query for all of them. It's called after the snippets have been read (and
+// their rows closed), which matters for SQLite's single connection.
+func (ts tagStatements) load(ctx context.Context, db *sql.DB, snippets []Snippet) error {
+	if len(snippets) == 0 {
+		return nil
+	}
+
+	// IN (?, ?, ...) with one placeholder per snippet, and a map to find
+	// each snippet from its id.
+	placeholders := make([]string, len(snippets))
+	args := make([]any, len(snippets))
+	index := make(map[int]int, len(snippets))
+	for i, s := range snippets {
+		placeholders[i] = ts.placeholder(i + 1)
+		args[i] = s.ID
+		index[s.ID] = i
+	}
+
+	statement := `SELECT st.snippet_id, t.name FROM snippet_tags AS st JOIN tags AS t ON t.id = st.tag_id
+WHERE st.snippet_id IN (` + strings.Join(placeholders, ", ") + `) ORDER BY t.name`
+
+	rows, err := db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return timeoutErr(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   int
+			name string
+		)
+		err = rows.Scan(&id, &name)
+		if err != nil {
+			return err
+		}
+		if i, ok := index[id]; ok {
+			snippets[i].Tags = append(snippets[i].Tags, name)
+		}
+	}
+
+	return timeoutErr(rows.Err())
+}
+
+// scanSnippets is scanSnippets() followed by load(), for the queries which
+// list snippets.
+func (ts tagStatements) scanSnippets(ctx context.Context, db *sql.DB, rows *sql.Rows) ([]Snippet, error) {
+	snippets, err := scanSnippets(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ts.load(ctx, db, snippets)
+	if err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// loadOne fills in the Tags of a single snippet.
+func (ts tagStatements) loadOne(ctx context.Context, db *sql.DB, s *Snippet) error {
+	list := []Snippet{*s}
+	err := ts.load(ctx, db, list)
+	s.Tags = list[0].Tags
+	return err
+}
+
+// tagJoin is added to snippetSelect by the ByTag() queries to keep only the
+// snippets with a given tag.
+const tagJoin = ` JOIN snippet_tags AS st ON st.snippet_id = s.id JOIN tags AS t ON t.id = st.tag_id`
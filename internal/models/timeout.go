@@ -0,0 +1,27 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// withTimeout returns a copy of ctx which is cancelled after d. A zero d means
+// no timeout, in which case ctx is only cancelled when its parent is (for
+// example, when the client goes away).
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// timeoutErr swaps a deadline exceeded error for ErrTimeout, so callers can
+// tell a slow database apart from a broken one. Any other error is returned
+// unchanged.
+func timeoutErr(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	return err
+}
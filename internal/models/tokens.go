@@ -0,0 +1,222 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The scopes a token can have. Read lets it fetch snippets, and write lets it
+// create and change them.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// Token is a personal API token. The token itself is only known when it's
+// created: we keep a hash of it, so a Token only has the details shown on
+// the tokens page. Expires is zero for tokens which never expire, and
+// LastUsed is zero until the token is first used.
+type Token struct {
+	ID       int
+	UserID   int
+	Name     string
+	Scopes   []string
+	Created  time.Time
+	Expires  time.Time
+	LastUsed time.Time
+}
+
+// HasScope reports whether the token was given scope.
+func (t Token) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// TokenStore describes the methods our handlers need from a token model.
+type TokenStore interface {
+	// Insert creates a token for userID which expires in expires days (or
+	// never, if expires is 0), and returns the plain-text token. This is the
+	// only time it's available.
+	Insert(ctx context.Context, userID int, name string, scopes []string, expires int) (string, error)
+	ByUser(ctx context.Context, userID int) ([]Token, error)
+	Revoke(ctx context.Context, id int, userID int) error
+	// Authenticate returns the token matching a plain-text token and records
+	// that it has been used, or returns ErrInvalidToken.
+	Authenticate(ctx context.Context, token string) (Token, error)
+}
+
+// tokenPrefix starts every token, so they're easy to spot (for instance by
+// secret scanners) if they end up somewhere they shouldn't.
+const tokenPrefix = "sbx_"
+
+// newToken generates a random token and returns it along with its hash.
+func newToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+
+	token = tokenPrefix + hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// hashToken returns the hex-encoded SHA-256 hash of a token, as stored in the
+// token_hash column. Tokens are long and random, so a fast hash is enough;
+// unlike passwords they don't need bcrypt.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// nullDays turns a number of days into a query parameter, with 0 meaning
+// never as NULL. Adding a NULL number of days to a timestamp gives NULL in
+// all of our SQL dialects, which is what's stored for "never expires".
+func nullDays(days int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(days), Valid: days != 0}
+}
+
+// tokenColumns lists the columns scanned by scanToken().
+const tokenColumns = `id, user_id, name, scopes, created, expires, last_used`
+
+// scanToken copies the columns listed in tokenColumns into a Token.
+func scanToken(row rowScanner) (Token, error) {
+	var (
+		t                 Token
+		scopes            string
+		expires, lastUsed sql.NullTime
+	)
+
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &scopes, &t.Created, &expires, &lastUsed)
+	if err != nil {
+		return Token{}, err
+	}
+
+	t.Scopes = strings.Split(scopes, ",")
+	t.Expires = expires.Time
+	t.LastUsed = lastUsed.Time
+
+	return t, nil
+}
+
+// scanTokens reads every row of a tokenColumns query and closes rows.
+func scanTokens(rows *sql.Rows) ([]Token, error) {
+	defer rows.Close()
+
+	var tokens []Token
+
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return tokens, nil
+}
+
+// TokenModel is the MySQL TokenStore.
+type TokenModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// Insert creates a new token and returns it in plain text.
+func (m *TokenModel) Insert(ctx context.Context, userID int, name string, scopes []string, expires int) (string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `INSERT INTO api_tokens (user_id, name, token_hash, scopes, created, expires)
+VALUES (?, ?, ?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+
+	_, err = m.DB.ExecContext(ctx, statement, userID, name, hash, strings.Join(scopes, ","), nullDays(expires))
+	if err != nil {
+		return "", timeoutErr(err)
+	}
+
+	return token, nil
+}
+
+// ByUser returns a user's tokens, newest first. Expired tokens are included,
+// so that the user can see why a script stopped working.
+func (m *TokenModel) ByUser(ctx context.Context, userID int) ([]Token, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `SELECT ` + tokenColumns + ` FROM api_tokens WHERE user_id = ? ORDER BY created DESC, id DESC`
+
+	rows, err := m.DB.QueryContext(ctx, statement, userID)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return scanTokens(rows)
+}
+
+// Revoke deletes one of a user's tokens, or returns ErrNoRecord if the user
+// has no such token.
+func (m *TokenModel) Revoke(ctx context.Context, id int, userID int) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return timeoutErr(err)
+	}
+
+	return revoked(result)
+}
+
+// Authenticate looks up an unexpired token by its hash and updates its
+// last_used time.
+func (m *TokenModel) Authenticate(ctx context.Context, token string) (Token, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `SELECT ` + tokenColumns + ` FROM api_tokens
+WHERE token_hash = ? AND (expires IS NULL OR expires > UTC_TIMESTAMP())`
+
+	t, err := scanToken(m.DB.QueryRowContext(ctx, statement, hashToken(token)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Token{}, ErrInvalidToken
+		}
+		return Token{}, timeoutErr(err)
+	}
+
+	_, err = m.DB.ExecContext(ctx, `UPDATE api_tokens SET last_used = UTC_TIMESTAMP() WHERE id = ?`, t.ID)
+	if err != nil {
+		return Token{}, timeoutErr(err)
+	}
+
+	return t, nil
+}
+
+// revoked turns the result of a DELETE of a single token into ErrNoRecord
+// if nothing was deleted.
+func revoked(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
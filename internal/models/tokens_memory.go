@@ -0,0 +1,119 @@
+package models
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryTokenModel is an in-memory implementation of TokenStore. Like the
+// other memory models, the zero value is ready to use.
+type MemoryTokenModel struct {
+	mu     sync.RWMutex
+	tokens map[string]Token // by token hash
+	lastID int
+}
+
+// Insert creates a new token and returns it in plain text.
+func (m *MemoryTokenModel) Insert(ctx context.Context, userID int, name string, scopes []string, expires int) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", timeoutErr(err)
+	}
+
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.tokens == nil {
+		m.tokens = make(map[string]Token)
+	}
+
+	created := time.Now().UTC().Truncate(time.Second)
+
+	t := Token{
+		ID:      m.lastID + 1,
+		UserID:  userID,
+		Name:    name,
+		Scopes:  slices.Clone(scopes),
+		Created: created,
+	}
+	if expires != 0 {
+		t.Expires = created.AddDate(0, 0, expires)
+	}
+
+	m.lastID++
+	m.tokens[hash] = t
+
+	return token, nil
+}
+
+// ByUser returns a user's tokens, newest first, including expired ones.
+func (m *MemoryTokenModel) ByUser(ctx context.Context, userID int) ([]Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tokens []Token
+	for _, t := range m.tokens {
+		if t.UserID == userID {
+			tokens = append(tokens, t)
+		}
+	}
+
+	// ORDER BY created DESC, id DESC
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID > tokens[j].ID })
+
+	return tokens, nil
+}
+
+// Revoke deletes one of a user's tokens, or returns ErrNoRecord if the user
+// has no such token.
+func (m *MemoryTokenModel) Revoke(ctx context.Context, id int, userID int) error {
+	if err := ctx.Err(); err != nil {
+		return timeoutErr(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, t := range m.tokens {
+		if t.ID == id && t.UserID == userID {
+			delete(m.tokens, hash)
+			return nil
+		}
+	}
+
+	return ErrNoRecord
+}
+
+// Authenticate looks up an unexpired token and updates its LastUsed time.
+func (m *MemoryTokenModel) Authenticate(ctx context.Context, token string) (Token, error) {
+	if err := ctx.Err(); err != nil {
+		return Token{}, timeoutErr(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hash := hashToken(token)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	t, ok := m.tokens[hash]
+	if !ok || (!t.Expires.IsZero() && !t.Expires.After(now)) {
+		return Token{}, ErrInvalidToken
+	}
+
+	t.LastUsed = now
+	m.tokens[hash] = t
+
+	return t, nil
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// PostgresTokenModel is the PostgreSQL TokenStore.
+type PostgresTokenModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// Insert creates a new token and returns it in plain text.
+func (m *PostgresTokenModel) Insert(ctx context.Context, userID int, name string, scopes []string, expires int) (string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `INSERT INTO api_tokens (user_id, name, token_hash, scopes, created, expires)
+VALUES ($1, $2, $3, $4, now() AT TIME ZONE 'UTC', (now() AT TIME ZONE 'UTC') + make_interval(days => $5))`
+
+	_, err = m.DB.ExecContext(ctx, statement, userID, name, hash, strings.Join(scopes, ","), nullDays(expires))
+	if err != nil {
+		return "", timeoutErr(err)
+	}
+
+	return token, nil
+}
+
+// ByUser returns a user's tokens, newest first. Expired tokens are included,
+// so that the user can see why a script stopped working.
+func (m *PostgresTokenModel) ByUser(ctx context.Context, userID int) ([]Token, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `SELECT ` + tokenColumns + ` FROM api_tokens WHERE user_id = $1 ORDER BY created DESC, id DESC`
+
+	rows, err := m.DB.QueryContext(ctx, statement, userID)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return scanTokens(rows)
+}
+
+// Revoke deletes one of a user's tokens, or returns ErrNoRecord if the user
+// has no such token.
+func (m *PostgresTokenModel) Revoke(ctx context.Context, id int, userID int) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return timeoutErr(err)
+	}
+
+	return revoked(result)
+}
+
+// Authenticate looks up an unexpired token by its hash and updates its
+// last_used time.
+func (m *PostgresTokenModel) Authenticate(ctx context.Context, token string) (Token, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `SELECT ` + tokenColumns + ` FROM api_tokens
+WHERE token_hash = $1 AND (expires IS NULL OR expires > now() AT TIME ZONE 'UTC')`
+
+	t, err := scanToken(m.DB.QueryRowContext(ctx, statement, hashToken(token)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Token{}, ErrInvalidToken
+		}
+		return Token{}, timeoutErr(err)
+	}
+
+	_, err = m.DB.ExecContext(ctx, `UPDATE api_tokens SET last_used = now() AT TIME ZONE 'UTC' WHERE id = $1`, t.ID)
+	if err != nil {
+		return Token{}, timeoutErr(err)
+	}
+
+	return t, nil
+}
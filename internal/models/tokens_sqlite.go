@@ -0,0 +1,91 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// SQLiteTokenModel is the SQLite TokenStore.
+type SQLiteTokenModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// Insert creates a new token and returns it in plain text.
+func (m *SQLiteTokenModel) Insert(ctx context.Context, userID int, name string, scopes []string, expires int) (string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `INSERT INTO api_tokens (user_id, name, token_hash, scopes, created, expires)
+VALUES (?, ?, ?, ?, datetime('now'), datetime('now', '+' || ? || ' days'))`
+
+	_, err = m.DB.ExecContext(ctx, statement, userID, name, hash, strings.Join(scopes, ","), nullDays(expires))
+	if err != nil {
+		return "", timeoutErr(err)
+	}
+
+	return token, nil
+}
+
+// ByUser returns a user's tokens, newest first. Expired tokens are included,
+// so that the user can see why a script stopped working.
+func (m *SQLiteTokenModel) ByUser(ctx context.Context, userID int) ([]Token, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `SELECT ` + tokenColumns + ` FROM api_tokens WHERE user_id = ? ORDER BY created DESC, id DESC`
+
+	rows, err := m.DB.QueryContext(ctx, statement, userID)
+	if err != nil {
+		return nil, timeoutErr(err)
+	}
+
+	return scanTokens(rows)
+}
+
+// Revoke deletes one of a user's tokens, or returns ErrNoRecord if the user
+// has no such token.
+func (m *SQLiteTokenModel) Revoke(ctx context.Context, id int, userID int) error {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return timeoutErr(err)
+	}
+
+	return revoked(result)
+}
+
+// Authenticate looks up an unexpired token by its hash and updates its
+// last_used time.
+func (m *SQLiteTokenModel) Authenticate(ctx context.Context, token string) (Token, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `SELECT ` + tokenColumns + ` FROM api_tokens
+WHERE token_hash = ? AND (expires IS NULL OR expires > datetime('now'))`
+
+	t, err := scanToken(m.DB.QueryRowContext(ctx, statement, hashToken(token)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Token{}, ErrInvalidToken
+		}
+		return Token{}, timeoutErr(err)
+	}
+
+	_, err = m.DB.ExecContext(ctx, `UPDATE api_tokens SET last_used = datetime('now') WHERE id = ?`, t.ID)
+	if err != nil {
+		return Token{}, timeoutErr(err)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Define a User type. Notice how the field names and types align with the
+// columns in the database "users" table?
+type User struct {
+	ID             int
+	Name           string
+	Email          string
+	HashedPassword []byte
+	Created        time.Time
+}
+
+// UserStore describes the methods our handlers need from a user model. Like
+// SnippetStore, it lets the handlers work with any backend.
+type UserStore interface {
+	Insert(ctx context.Context, name, email, password string) error
+	Authenticate(ctx context.Context, email, password string) (int, error)
+	Exists(ctx context.Context, id int) (bool, error)
+	IDByEmail(ctx context.Context, email string) (int, error)
+}
+
+// Define a new UserModel type which wraps a database connection pool.
+type UserModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// bcryptCost is the work factor used when hashing passwords. 12 takes a
+// couple of hundred milliseconds, which is slow for attackers but fine for a
+// signup form.
+const bcryptCost = 12
+
+// We'll use the Insert method to add a new record to the "users" table.
+func (m *UserModel) Insert(ctx context.Context, name, email, password string) error {
+	// Create a bcrypt hash of the plain-text password.
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `INSERT INTO users (name, email, hashed_password, created)
+VALUES(?, ?, ?, UTC_TIMESTAMP())`
+
+	_, err = m.DB.ExecContext(ctx, statement, name, email, string(hashedPassword))
+	if err != nil {
+		// If this returns an error, we use the errors.As() function to check
+		// whether the error has the type *mysql.MySQLError. If it does, the
+		// error will be assigned to the mySQLError variable. We can then check
+		// whether or not the error relates to our users_uc_email key by
+		// checking if the error code equals 1062 and the contents of the error
+		// message string. If it does, we return an ErrDuplicateEmail error.
+		var mySQLError *mysql.MySQLError
+		if errors.As(err, &mySQLError) {
+			if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_email") {
+				return ErrDuplicateEmail
+			}
+		}
+		return timeoutErr(err)
+	}
+
+	return nil
+}
+
+// We'll use the Authenticate method to verify whether a user exists with
+// the provided email address and password. This will return the relevant
+// user ID if they do.
+func (m *UserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	// Retrieve the id and hashed password associated with the given email. If
+	// no matching email exists we return the ErrInvalidCredentials error.
+	var id int
+	var hashedPassword []byte
+
+	statement := "SELECT id, hashed_password FROM users WHERE email = ?"
+
+	err := m.DB.QueryRowContext(ctx, statement, email).Scan(&id, &hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		}
+		return 0, timeoutErr(err)
+	}
+
+	return checkPassword(id, hashedPassword, password)
+}
+
+// We'll use the Exists method to check if a user exists with a specific ID.
+func (m *UserModel) Exists(ctx context.Context, id int) (bool, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	var exists bool
+
+	statement := "SELECT EXISTS(SELECT true FROM users WHERE id = ?)"
+
+	err := m.DB.QueryRowContext(ctx, statement, id).Scan(&exists)
+	return exists, timeoutErr(err)
+}
+
+// IDByEmail returns the id of the user with the given email address, or
+// ErrNoRecord if there isn't one.
+func (m *UserModel) IDByEmail(ctx context.Context, email string) (int, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	var id int
+
+	statement := "SELECT id FROM users WHERE email = ?"
+
+	err := m.DB.QueryRowContext(ctx, statement, email).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, timeoutErr(err)
+	}
+
+	return id, nil
+}
+
+// checkPassword compares the plain-text password with the stored bcrypt hash.
+// It returns the user's id if they match, or ErrInvalidCredentials if not.
+func checkPassword(id int, hashedPassword []byte, password string) (int, error) {
+	err := bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return 0, ErrInvalidCredentials
+		}
+		return 0, err
+	}
+
+	// Otherwise, the password is correct. Return the user ID.
+	return id, nil
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MemoryUserModel is an in-memory implementation of UserStore. Like
+// MemorySnippetModel, the zero value is ready to use.
+type MemoryUserModel struct {
+	mu     sync.RWMutex
+	users  map[int]User
+	lastID int
+}
+
+// Insert adds a new user, or returns ErrDuplicateEmail if the email address
+// is already taken.
+func (m *MemoryUserModel) Insert(ctx context.Context, name, email, password string) error {
+	if err := ctx.Err(); err != nil {
+		return timeoutErr(err)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.users == nil {
+		m.users = make(map[int]User)
+	}
+
+	// the same check as the users_uc_email constraint
+	for _, u := range m.users {
+		if u.Email == email {
+			return ErrDuplicateEmail
+		}
+	}
+
+	m.lastID++
+	m.users[m.lastID] = User{
+		ID:             m.lastID,
+		Name:           name,
+		Email:          email,
+		HashedPassword: hashedPassword,
+		Created:        time.Now().UTC().Truncate(time.Second),
+	}
+
+	return nil
+}
+
+// Authenticate returns the id of the user with the given email address and
+// password, or ErrInvalidCredentials.
+func (m *MemoryUserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	var (
+		user  User
+		found bool
+	)
+	for _, u := range m.users {
+		if u.Email == email {
+			user, found = u, true
+			break
+		}
+	}
+	m.mu.RUnlock()
+
+	if !found {
+		return 0, ErrInvalidCredentials
+	}
+
+	return checkPassword(user.ID, user.HashedPassword, password)
+}
+
+// Exists reports whether a user with the given id exists.
+func (m *MemoryUserModel) Exists(ctx context.Context, id int) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.users[id]
+	return ok, nil
+}
+
+// IDByEmail returns the id of the user with the given email address, or
+// ErrNoRecord if there isn't one.
+func (m *MemoryUserModel) IDByEmail(ctx context.Context, email string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, timeoutErr(err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, u := range m.users {
+		if u.Email == email {
+			return u.ID, nil
+		}
+	}
+
+	return 0, ErrNoRecord
+}
+
+// name returns the name of the user with the given id, or "" if there's no
+// such user. It stands in for the join on the users table in snippetSelect.
+func (m *MemoryUserModel) name(id int) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.users[id].Name
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PostgresUserModel is the PostgreSQL version of UserModel.
+type PostgresUserModel struct {
+	DB      *sql.DB
+	Timeout time.Duration
+}
+
+// We'll use the Insert method to add a new record to the "users" table.
+func (m *PostgresUserModel) Insert(ctx context.Context, name, email, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	statement := `INSERT INTO users (name, email, hashed_password, created)
+VALUES($1, $2, $3, now() AT TIME ZONE 'UTC')`
+
+	_, err = m.DB.ExecContext(ctx, statement, name, email, string(hashedPassword))
+	if err != nil {
+		// 23505 is Postgres' unique_violation error code.
+		var pgError *pgconn.PgError
+		if errors.As(err, &pgError) {
+			if pgError.Code == "23505" && pgError.ConstraintName == "users_uc_email" {
+				return ErrDuplicateEmail
+			}
+		}
+		return timeoutErr(err)
+	}
+
+	return nil
+}
+
+// We'll use the Authenticate method to verify whether a user exists with
+// the provided email address and password.
+func (m *PostgresUserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	var id int
+	var hashedPassword []byte
+
+	statement := "SELECT id, hashed_password FROM users WHERE email = $1"
+
+	err := m.DB.QueryRowContext(ctx, statement, email).Scan(&id, &hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		}
+		return 0, timeoutErr(err)
+	}
+
+	return checkPassword(id, hashedPassword, password)
+}
+
+// We'll use the Exists method to check if a user exists with a specific ID.
+func (m *PostgresUserModel) Exists(ctx context.Context, id int) (bool, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	var exists bool
+
+	statement := "SELECT EXISTS(SELECT true FROM users WHERE id = $1)"
+
+	err := m.DB.QueryRowContext(ctx, statement, id).Scan(&exists)
+	return exists, timeoutErr(err)
+}
+
+// IDByEmail returns the id of the user with the given email address, or
+// ErrNoRecord if there isn't one.
+func (m *PostgresUserModel) IDByEmail(ctx context.Context, email string) (int, error) {
+	ctx, cancel := withTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	var id int
+
+	statement := "SELECT id FROM users WHERE email = $1"
+
+	err := m.DB.QueryRowContext(ctx, statement, email).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNoRecord
+		}
+		return 0, timeoutErr(err)
+	}
+
+	return id, nil
+}
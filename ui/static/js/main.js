@@ -5,4 +5,71 @@ for (var i = 0; i < navLinks.length; i++) {
 		link.classList.add("live");
 		break;
 	}
-}
\ No newline at end of file
+}
+
+// Line links in highlighted snippets. Each line number is a link to its own
+// anchor (#L42); here we highlight the line, or the range of lines in a
+// fragment like #L10-L20. Shift-click on another number selects everything
+// from the first selected line to that one.
+var lineNumbers = document.querySelectorAll(".snippet .lntd .lnt");
+var codeLines = document.querySelectorAll(".snippet .lntd .line");
+
+function selectedLines() {
+	var match = /^#L(\d+)(?:-L(\d+))?$/.exec(window.location.hash);
+	if (match === null) {
+		return null;
+	}
+	var from = parseInt(match[1], 10);
+	var to = match[2] ? parseInt(match[2], 10) : from;
+	return {from: Math.min(from, to), to: Math.max(from, to)};
+}
+
+function highlightLines(scroll) {
+	var range = selectedLines();
+	if (range === null) {
+		return;
+	}
+
+	// Clear any earlier selection, including the one the server highlights
+	// on a ?lines= page.
+	var highlighted = document.querySelectorAll(".snippet .lntd .hl");
+	for (var i = 0; i < highlighted.length; i++) {
+		highlighted[i].classList.remove("hl");
+	}
+
+	// The line numbers and lines of code are in the same order.
+	for (var i = 0; i < lineNumbers.length; i++) {
+		var n = parseInt(lineNumbers[i].id.slice(1), 10);
+		if (n >= range.from && n <= range.to) {
+			lineNumbers[i].classList.add("hl");
+			codeLines[i].classList.add("hl");
+		}
+	}
+
+	// The browser can't scroll to a range by itself, as no element has its id.
+	var first = document.getElementById("L" + range.from);
+	if (scroll && first !== null) {
+		first.scrollIntoView();
+	}
+}
+
+if (lineNumbers.length > 0 && lineNumbers.length === codeLines.length) {
+	highlightLines(true);
+	window.addEventListener("hashchange", function() {
+		highlightLines(false);
+	});
+
+	for (var i = 0; i < lineNumbers.length; i++) {
+		lineNumbers[i].addEventListener("click", function(event) {
+			var range = selectedLines();
+			if (!event.shiftKey || range === null) {
+				return;
+			}
+			event.preventDefault();
+			var n = parseInt(this.id.slice(1), 10);
+			var from = Math.min(range.from, n);
+			var to = Math.max(range.from, n);
+			window.location.hash = from === to ? "#L" + from : "#L" + from + "-L" + to;
+		});
+	}
+}